@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
@@ -27,6 +28,7 @@ type AuthMiddleware struct {
 
 	logger        *zap.SugaredLogger
 	revokedTokens map[string]struct{}
+	revokedMux    sync.RWMutex
 }
 
 func NewAuthMiddleware(
@@ -131,7 +133,18 @@ func (m *AuthMiddleware) Check(serviceJWT, requestedMethod string) (*models.Auth
 	return claims, nil
 }
 
+// Revoke отзывает токен: следующие запросы с ним получат 403
+func (m *AuthMiddleware) Revoke(id string) {
+	m.revokedMux.Lock()
+	defer m.revokedMux.Unlock()
+
+	m.revokedTokens[id] = struct{}{}
+}
+
 func (m *AuthMiddleware) isRevoked(id string) bool {
+	m.revokedMux.RLock()
+	defer m.revokedMux.RUnlock()
+
 	_, has := m.revokedTokens[id]
 
 	return has
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+
+	"spendings-backend/internal/models"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// recordingWriter пишет ответ клиенту и запоминает его для повторов запроса
+type recordingWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(statusCode int) {
+	if rw.statusCode == 0 {
+		rw.statusCode = statusCode
+	}
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *recordingWriter) Write(body []byte) (int, error) {
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
+	rw.body.Write(body)
+
+	return rw.ResponseWriter.Write(body)
+}
+
+// idempotent повторяет сохраненный ответ, если запрос пришел с уже использованным
+// заголовком Idempotency-Key и тем же телом. Ответы с ошибкой сервера не сохраняются,
+// чтобы запрос можно было повторить.
+func (r *Router) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		key := request.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next(writer, request)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, r.maxRequestBodySize))
+		if err != nil {
+			r.sendErrorResponse(writer, request, fmt.Errorf("%w: can't read request body: %w", models.ErrBadRequest, err))
+			return
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(request.Method + " " + request.URL.Path + "\n"))
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		stored, err := r.idempotencyService.Begin(request.Context(), key, fingerprint)
+		if err != nil {
+			r.sendErrorResponse(writer, request, fmt.Errorf("Idempotency: %w", err))
+			return
+		}
+
+		if stored != nil {
+			writer.Header().Set(idempotentReplayedHeader, "true")
+			r.sendResponse(writer, request, stored.StatusCode, stored.Body)
+			return
+		}
+
+		// Ключ освобождается и при панике обработчика, иначе повторы получали бы конфликт до истечения TTL
+		completed := false
+		defer func() {
+			if !completed {
+				r.idempotencyService.Release(request.Context(), key)
+			}
+		}()
+
+		recorder := &recordingWriter{ResponseWriter: writer}
+		next(recorder, request)
+
+		if recorder.statusCode == 0 || recorder.statusCode >= http.StatusInternalServerError {
+			return
+		}
+
+		r.idempotencyService.Complete(request.Context(), key, models.IdempotentResponse{
+			StatusCode: recorder.statusCode,
+			Body:       recorder.body.Bytes(),
+		})
+		completed = true
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rs/cors"
@@ -20,6 +23,12 @@ var (
 	errInvalidPaginationParameter = errors.New("invalid pagination parameter")
 	errEmptyID                    = errors.New("empty id")
 	errEmptyName                  = errors.New("empty name")
+	errInvalidMonthsParameter     = errors.New("invalid months parameter")
+	errInvalidAmountParameter     = errors.New("invalid amount parameter")
+	errInvalidDelimiter           = errors.New("delimiter must be a single character")
+	errInvalidBoolParameter       = errors.New("invalid boolean parameter")
+	errInvalidExportFormat        = errors.New("invalid export format, must be one of: csv, xlsx, json")
+	errInvalidMonthParameter      = errors.New("invalid month parameter, must be in YYYY-MM format")
 	errJsonDecode                 = fmt.Errorf("%w: json body invalid", models.ErrBadRequest)
 )
 
@@ -30,17 +39,74 @@ type TokenService interface {
 // New service interfaces for financial tracking
 type StatisticsService interface {
 	GetStatistics(ctx context.Context, fromDate, toDate time.Time) (*models.StatisticsResponse, error)
+	GetForecast(ctx context.Context, months int) (*models.ForecastResponse, error)
+	GetPayeeStatistics(ctx context.Context, fromDate, toDate time.Time) ([]models.PayeeStatistics, error)
 }
 
 type TransactionsService interface {
-	GetTransactions(ctx context.Context, categories []string, fromDate, toDate time.Time, page, pageSize int) (*models.TransactionsResponse, error)
+	GetTransactions(ctx context.Context, filter models.TransactionsFilter, pagination models.TransactionsPagination) (*models.TransactionsResponse, error)
 	CreateTransaction(ctx context.Context, req models.CreateTransactionRequest) (*models.CreateTransactionResponse, error)
+	CreateTransactionFromReceipt(ctx context.Context, req models.ReceiptRequest) (*models.ReceiptResponse, error)
+	BatchTransactions(ctx context.Context, req models.BatchRequest) (*models.BatchResult, error)
 	DeleteTransaction(ctx context.Context, id string) error
+	GetTrash(ctx context.Context) (*models.TrashResponse, error)
+	RestoreTransaction(ctx context.Context, id string) (*models.Transaction, error)
+	PurgeTrashTransaction(ctx context.Context, id string) error
+	EmptyTrash(ctx context.Context) error
+	GetDuplicates(ctx context.Context) (*models.DuplicatesResponse, error)
+	GetTags(ctx context.Context, prefix string, limit int) ([]models.TagUsage, error)
+	ApplyRules(ctx context.Context, dryRun bool) (*models.ApplyRulesResult, error)
+	SuggestCategories(ctx context.Context, title string, amount *float64, limit int) ([]models.CategorySuggestion, error)
+	AddAttachment(ctx context.Context, transactionID, fileName string, reader io.Reader) (*models.Attachment, error)
+	OpenAttachment(ctx context.Context, transactionID, attachmentID string) (*models.Attachment, io.ReadSeekCloser, error)
+	DeleteAttachment(ctx context.Context, transactionID, attachmentID string) error
+}
+
+type RulesService interface {
+	GetRules(ctx context.Context) ([]models.Rule, error)
+	CreateRule(ctx context.Context, rule models.Rule) (*models.Rule, error)
+	UpdateRule(ctx context.Context, id string, rule models.Rule) (*models.Rule, error)
+	DeleteRule(ctx context.Context, id string) error
+}
+
+type PayeesService interface {
+	GetPayees(ctx context.Context) ([]models.Payee, error)
+	CreatePayee(ctx context.Context, payee models.Payee) (*models.Payee, error)
+	UpdatePayee(ctx context.Context, id string, payee models.Payee) (*models.Payee, error)
+	DeletePayee(ctx context.Context, id string) error
 }
 
 type CategoriesService interface {
-	GetCategories(ctx context.Context, nameFilter string) ([]models.Category, error)
+	GetCategories(ctx context.Context, nameFilter string, includeArchived bool) ([]models.Category, error)
 	CreateCategory(ctx context.Context, category models.Category) error
+	UpdateCategory(ctx context.Context, name string, request models.UpdateCategoryRequest) (*models.Category, error)
+	ReorderCategories(ctx context.Context, request models.ReorderCategoriesRequest) error
+}
+
+type ExportService interface {
+	Export(ctx context.Context, writer io.Writer, options models.ExportOptions) error
+}
+
+type ReportService interface {
+	GetMonthlyReport(ctx context.Context, month time.Time) ([]byte, error)
+}
+
+type AccountService interface {
+	Export(ctx context.Context) *models.AccountArchive
+	Import(ctx context.Context, reader io.Reader, mode string) (*models.AccountImportResult, error)
+	DeleteAccount(ctx context.Context) error
+	DeleteManagedAccount(ctx context.Context, userID string) error
+}
+
+type IdempotencyService interface {
+	Begin(ctx context.Context, key, fingerprint string) (*models.IdempotentResponse, error)
+	Complete(ctx context.Context, key string, response models.IdempotentResponse)
+	Release(ctx context.Context, key string)
+}
+
+type ImportService interface {
+	ImportCSV(ctx context.Context, reader io.Reader, csvOptions models.CSVImportOptions, options models.ImportOptions) (*models.ImportResult, error)
+	ImportStatement(ctx context.Context, format string, reader io.Reader, options models.ImportOptions) (*models.ImportResult, error)
 }
 
 type Router struct {
@@ -51,6 +117,15 @@ type Router struct {
 	statisticsService   StatisticsService
 	transactionsService TransactionsService
 	categoriesService   CategoriesService
+	rulesService        RulesService
+	payeesService       PayeesService
+	importService       ImportService
+	exportService       ExportService
+	reportService       ReportService
+	accountService      AccountService
+	idempotencyService  IdempotencyService
+
+	maxRequestBodySize int64
 
 	logger *zap.SugaredLogger
 }
@@ -61,6 +136,13 @@ func NewRouter(
 	statisticsService StatisticsService,
 	transactionsService TransactionsService,
 	categoriesService CategoriesService,
+	rulesService RulesService,
+	payeesService PayeesService,
+	importService ImportService,
+	exportService ExportService,
+	reportService ReportService,
+	accountService AccountService,
+	idempotencyService IdempotencyService,
 	authMiddleware func(next http.HandlerFunc) http.HandlerFunc,
 	loggingMiddleware func(next http.HandlerFunc) http.HandlerFunc,
 	logger *zap.SugaredLogger,
@@ -79,6 +161,14 @@ func NewRouter(
 		statisticsService:   statisticsService,
 		transactionsService: transactionsService,
 		categoriesService:   categoriesService,
+		rulesService:        rulesService,
+		payeesService:       payeesService,
+		importService:       importService,
+		exportService:       exportService,
+		reportService:       reportService,
+		accountService:      accountService,
+		idempotencyService:  idempotencyService,
+		maxRequestBodySize:  int64(cfg.MaxRequestBodySizeMb) << 20,
 		logger:              logger,
 	}
 
@@ -87,11 +177,44 @@ func NewRouter(
 
 	// New API routes
 	innerRouter.HandleFunc("GET /api/statistics", authMiddleware(loggingMiddleware(appRouter.getStatistics)))
+	innerRouter.HandleFunc("GET /api/statistics/forecast", authMiddleware(loggingMiddleware(appRouter.getForecast)))
 	innerRouter.HandleFunc("GET /api/transactions", authMiddleware(loggingMiddleware(appRouter.getTransactions)))
-	innerRouter.HandleFunc("POST /api/transactions", authMiddleware(loggingMiddleware(appRouter.createTransaction)))
+	innerRouter.HandleFunc("GET /api/transactions/duplicates", authMiddleware(loggingMiddleware(appRouter.getDuplicates)))
+	innerRouter.HandleFunc("POST /api/transactions", authMiddleware(loggingMiddleware(appRouter.idempotent(appRouter.createTransaction))))
+	innerRouter.HandleFunc("POST /api/transactions/receipt", authMiddleware(loggingMiddleware(appRouter.createTransactionFromReceipt)))
+	innerRouter.HandleFunc("POST /api/transactions/batch", authMiddleware(loggingMiddleware(appRouter.idempotent(appRouter.batchTransactions))))
 	innerRouter.HandleFunc("DELETE /api/transactions/{id}", authMiddleware(loggingMiddleware(appRouter.deleteTransaction)))
+	innerRouter.HandleFunc("GET /api/transactions/trash", authMiddleware(loggingMiddleware(appRouter.getTrash)))
+	innerRouter.HandleFunc("DELETE /api/transactions/trash", authMiddleware(loggingMiddleware(appRouter.emptyTrash)))
+	innerRouter.HandleFunc("POST /api/transactions/trash/{id}/restore", authMiddleware(loggingMiddleware(appRouter.restoreTransaction)))
+	innerRouter.HandleFunc("DELETE /api/transactions/trash/{id}", authMiddleware(loggingMiddleware(appRouter.purgeTrashTransaction)))
+	innerRouter.HandleFunc("POST /api/transactions/{id}/attachments", authMiddleware(loggingMiddleware(appRouter.addAttachment)))
+	innerRouter.HandleFunc("GET /api/transactions/{id}/attachments/{attachmentId}", authMiddleware(loggingMiddleware(appRouter.getAttachment)))
+	innerRouter.HandleFunc("DELETE /api/transactions/{id}/attachments/{attachmentId}", authMiddleware(loggingMiddleware(appRouter.deleteAttachment)))
+	innerRouter.HandleFunc("GET /api/tags", authMiddleware(loggingMiddleware(appRouter.getTags)))
 	innerRouter.HandleFunc("GET /api/categories", authMiddleware(loggingMiddleware(appRouter.getCategories)))
-	innerRouter.HandleFunc("POST /api/categories", authMiddleware(loggingMiddleware(appRouter.createCategory)))
+	innerRouter.HandleFunc("POST /api/categories", authMiddleware(loggingMiddleware(appRouter.idempotent(appRouter.createCategory))))
+	innerRouter.HandleFunc("GET /api/categories/suggest", authMiddleware(loggingMiddleware(appRouter.suggestCategories)))
+	innerRouter.HandleFunc("PUT /api/categories/order", authMiddleware(loggingMiddleware(appRouter.reorderCategories)))
+	innerRouter.HandleFunc("PATCH /api/categories/{name}", authMiddleware(loggingMiddleware(appRouter.updateCategory)))
+	innerRouter.HandleFunc("GET /api/rules", authMiddleware(loggingMiddleware(appRouter.getRules)))
+	innerRouter.HandleFunc("POST /api/rules", authMiddleware(loggingMiddleware(appRouter.idempotent(appRouter.createRule))))
+	innerRouter.HandleFunc("POST /api/rules/apply", authMiddleware(loggingMiddleware(appRouter.applyRules)))
+	innerRouter.HandleFunc("PUT /api/rules/{id}", authMiddleware(loggingMiddleware(appRouter.updateRule)))
+	innerRouter.HandleFunc("DELETE /api/rules/{id}", authMiddleware(loggingMiddleware(appRouter.deleteRule)))
+	innerRouter.HandleFunc("GET /api/payees", authMiddleware(loggingMiddleware(appRouter.getPayees)))
+	innerRouter.HandleFunc("POST /api/payees", authMiddleware(loggingMiddleware(appRouter.idempotent(appRouter.createPayee))))
+	innerRouter.HandleFunc("GET /api/payees/statistics", authMiddleware(loggingMiddleware(appRouter.getPayeeStatistics)))
+	innerRouter.HandleFunc("PUT /api/payees/{id}", authMiddleware(loggingMiddleware(appRouter.updatePayee)))
+	innerRouter.HandleFunc("DELETE /api/payees/{id}", authMiddleware(loggingMiddleware(appRouter.deletePayee)))
+	innerRouter.HandleFunc("POST /api/import/csv", authMiddleware(loggingMiddleware(appRouter.importCSV)))
+	innerRouter.HandleFunc("POST /api/import/{format}", authMiddleware(loggingMiddleware(appRouter.importStatement)))
+	innerRouter.HandleFunc("GET /api/export", authMiddleware(loggingMiddleware(appRouter.export)))
+	innerRouter.HandleFunc("GET /api/reports/monthly", authMiddleware(loggingMiddleware(appRouter.getMonthlyReport)))
+	innerRouter.HandleFunc("GET /api/me/export", authMiddleware(loggingMiddleware(appRouter.exportAccount)))
+	innerRouter.HandleFunc("POST /api/me/import", authMiddleware(loggingMiddleware(appRouter.importAccount)))
+	innerRouter.HandleFunc("DELETE /api/me", authMiddleware(loggingMiddleware(appRouter.deleteAccount)))
+	innerRouter.HandleFunc("DELETE /api/users/{id}", authMiddleware(loggingMiddleware(appRouter.deleteManagedAccount)))
 
 	// Health check endpoint
 	innerRouter.HandleFunc("GET /api/health", appRouter.healthCheck)
@@ -145,6 +268,16 @@ func (r *Router) sendErrorResponse(response http.ResponseWriter, request *http.R
 
 		r.writeError(response, request, err)
 
+		return
+	case errors.Is(err, models.ErrConflict):
+		response.WriteHeader(http.StatusConflict)
+		r.logger.With(
+			"module", "api",
+			"request_url", request.Method+": "+request.URL.Path,
+		).Warn(err)
+
+		r.writeError(response, request, err)
+
 		return
 	case errors.Is(err, models.ErrUnauthorized):
 		response.WriteHeader(http.StatusUnauthorized)
@@ -220,6 +353,32 @@ func (r *Router) getStatistics(writer http.ResponseWriter, request *http.Request
 	r.sendResponse(writer, request, http.StatusOK, buf)
 }
 
+func (r *Router) getForecast(writer http.ResponseWriter, request *http.Request) {
+	months := models.DefaultForecastMonths
+
+	if monthsStr := request.URL.Query().Get("months"); monthsStr != "" {
+		var err error
+		if months, err = strconv.Atoi(monthsStr); err != nil {
+			r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w: %w", models.ErrBadRequest, errInvalidMonthsParameter, err))
+			return
+		}
+	}
+
+	forecast, err := r.statisticsService.GetForecast(request.Context(), months)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("GetForecast: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(forecast)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
 func (r *Router) getTransactions(writer http.ResponseWriter, request *http.Request) {
 	// Parse query parameters
 	categories := request.URL.Query()["category"]
@@ -246,6 +405,18 @@ func (r *Router) getTransactions(writer http.ResponseWriter, request *http.Reque
 		}
 	}
 
+	minAmount, err := getAmountParameter(request, "minAmount")
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
+		return
+	}
+
+	maxAmount, err := getAmountParameter(request, "maxAmount")
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
+		return
+	}
+
 	page, err := getPaginationParameter(request, "page", 1)
 	if err != nil {
 		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
@@ -258,7 +429,26 @@ func (r *Router) getTransactions(writer http.ResponseWriter, request *http.Reque
 		return
 	}
 
-	transactions, err := r.transactionsService.GetTransactions(request.Context(), categories, fromDate, toDate, page, pageSize)
+	filter := models.TransactionsFilter{
+		Categories: categories,
+		FromDate:   fromDate,
+		ToDate:     toDate,
+		Query:      request.URL.Query().Get("q"),
+		MinAmount:  minAmount,
+		MaxAmount:  maxAmount,
+		Tags:       request.URL.Query()["tag"],
+		TagsMatch:  request.URL.Query().Get("tagMatch"),
+	}
+
+	pagination := models.TransactionsPagination{
+		Page:     page,
+		PageSize: pageSize,
+		SortBy:   request.URL.Query().Get("sort"),
+		Order:    request.URL.Query().Get("order"),
+		Cursor:   request.URL.Query().Get("cursor"),
+	}
+
+	transactions, err := r.transactionsService.GetTransactions(request.Context(), filter, pagination)
 	if err != nil {
 		r.sendErrorResponse(writer, request, fmt.Errorf("GetTransactions: %w", err))
 		return
@@ -297,6 +487,57 @@ func (r *Router) createTransaction(writer http.ResponseWriter, request *http.Req
 	r.sendResponse(writer, request, http.StatusCreated, buf)
 }
 
+func (r *Router) createTransactionFromReceipt(writer http.ResponseWriter, request *http.Request) {
+	var requestBody models.ReceiptRequest
+	if err := json.NewDecoder(request.Body).Decode(&requestBody); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", errJsonDecode, err))
+		return
+	}
+
+	response, err := r.transactionsService.CreateTransactionFromReceipt(request.Context(), requestBody)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("CreateTransactionFromReceipt: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(response)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	// Повторный скан того же чека возвращает существующую транзакцию
+	code := http.StatusCreated
+	if !response.Created {
+		code = http.StatusOK
+	}
+
+	r.sendResponse(writer, request, code, buf)
+}
+
+func (r *Router) batchTransactions(writer http.ResponseWriter, request *http.Request) {
+	var requestBody models.BatchRequest
+	if err := json.NewDecoder(request.Body).Decode(&requestBody); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", errJsonDecode, err))
+		return
+	}
+
+	response, err := r.transactionsService.BatchTransactions(request.Context(), requestBody)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("BatchTransactions: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(response)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	// Невыполненный пакет - не ошибка запроса: результаты элементов объясняют, что не так
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
 func (r *Router) deleteTransaction(writer http.ResponseWriter, request *http.Request) {
 	id := request.PathValue("id")
 	if id == "" {
@@ -313,10 +554,183 @@ func (r *Router) deleteTransaction(writer http.ResponseWriter, request *http.Req
 	writer.WriteHeader(http.StatusNoContent)
 }
 
+func (r *Router) getTrash(writer http.ResponseWriter, request *http.Request) {
+	response, err := r.transactionsService.GetTrash(request.Context())
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("GetTrash: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(response)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) restoreTransaction(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+	if id == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
+		return
+	}
+
+	transaction, err := r.transactionsService.RestoreTransaction(request.Context(), id)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("RestoreTransaction: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(transaction)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) purgeTrashTransaction(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+	if id == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
+		return
+	}
+
+	if err := r.transactionsService.PurgeTrashTransaction(request.Context(), id); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("PurgeTrashTransaction: %w", err))
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Router) emptyTrash(writer http.ResponseWriter, request *http.Request) {
+	if err := r.transactionsService.EmptyTrash(request.Context()); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("EmptyTrash: %w", err))
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Router) addAttachment(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+	if id == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
+		return
+	}
+
+	request.Body = http.MaxBytesReader(writer, request.Body, r.maxRequestBodySize)
+
+	file, header, err := request.FormFile("file")
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: can't read file: %w", models.ErrBadRequest, err))
+		return
+	}
+	defer file.Close()
+
+	attachment, err := r.transactionsService.AddAttachment(request.Context(), id, header.Filename, file)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("AddAttachment: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(attachment)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusCreated, buf)
+}
+
+func (r *Router) getAttachment(writer http.ResponseWriter, request *http.Request) {
+	id, attachmentID := request.PathValue("id"), request.PathValue("attachmentId")
+	if id == "" || attachmentID == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
+		return
+	}
+
+	attachment, file, err := r.transactionsService.OpenAttachment(request.Context(), id, attachmentID)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("OpenAttachment: %w", err))
+		return
+	}
+	defer file.Close()
+
+	// Тип задан при загрузке по содержимому файла, браузер не должен угадывать его сам
+	writer.Header().Set("Content-Type", attachment.ContentType)
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	writer.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.FileName}))
+	http.ServeContent(writer, request, attachment.FileName, attachment.UploadedAt, file)
+}
+
+func (r *Router) deleteAttachment(writer http.ResponseWriter, request *http.Request) {
+	id, attachmentID := request.PathValue("id"), request.PathValue("attachmentId")
+	if id == "" || attachmentID == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
+		return
+	}
+
+	if err := r.transactionsService.DeleteAttachment(request.Context(), id, attachmentID); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("DeleteAttachment: %w", err))
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Router) getTags(writer http.ResponseWriter, request *http.Request) {
+	limit, err := getPaginationParameter(request, "limit", models.DefaultTagsLimit)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
+		return
+	}
+
+	tags, err := r.transactionsService.GetTags(request.Context(), request.URL.Query().Get("q"), limit)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("GetTags: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(tags)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) getDuplicates(writer http.ResponseWriter, request *http.Request) {
+	duplicates, err := r.transactionsService.GetDuplicates(request.Context())
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("GetDuplicates: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(duplicates)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
 func (r *Router) getCategories(writer http.ResponseWriter, request *http.Request) {
 	nameFilter := request.URL.Query().Get("name")
 
-	categories, err := r.categoriesService.GetCategories(request.Context(), nameFilter)
+	includeArchived, err := getBoolParameter(request.URL.Query().Get("includeArchived"), "includeArchived", false)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
+		return
+	}
+
+	categories, err := r.categoriesService.GetCategories(request.Context(), nameFilter, includeArchived)
 	if err != nil {
 		r.sendErrorResponse(writer, request, fmt.Errorf("GetCategories: %w", err))
 		return
@@ -355,24 +769,26 @@ func (r *Router) createCategory(writer http.ResponseWriter, request *http.Reques
 	r.sendResponse(writer, request, http.StatusCreated, buf)
 }
 
-func (r *Router) createToken(writer http.ResponseWriter, request *http.Request) {
-	name := request.URL.Query().Get("name")
-	if name == "" {
-		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyName))
+func (r *Router) suggestCategories(writer http.ResponseWriter, request *http.Request) {
+	limit, err := getPaginationParameter(request, "limit", models.DefaultSuggestionsLimit)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
 		return
 	}
 
-	token, err := r.tokenService.GenerateToken(request.Context(), name, false)
+	amount, err := getAmountParameter(request, "amount")
 	if err != nil {
-		r.sendErrorResponse(writer, request, fmt.Errorf("CreateToken: %w", err))
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
 		return
 	}
 
-	responseBody := TokenResponse{
-		Token: token,
+	suggestions, err := r.transactionsService.SuggestCategories(request.Context(), request.URL.Query().Get("title"), amount, limit)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("SuggestCategories: %w", err))
+		return
 	}
 
-	buf, err := json.Marshal(responseBody)
+	buf, err := json.Marshal(suggestions)
 	if err != nil {
 		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
 		return
@@ -381,24 +797,26 @@ func (r *Router) createToken(writer http.ResponseWriter, request *http.Request)
 	r.sendResponse(writer, request, http.StatusOK, buf)
 }
 
-func (r *Router) createTeacherToken(writer http.ResponseWriter, request *http.Request) {
-	name := request.URL.Query().Get("name")
+func (r *Router) updateCategory(writer http.ResponseWriter, request *http.Request) {
+	name := request.PathValue("name")
 	if name == "" {
-		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyName))
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
 		return
 	}
 
-	token, err := r.tokenService.GenerateToken(request.Context(), name, true)
-	if err != nil {
-		r.sendErrorResponse(writer, request, fmt.Errorf("CreateToken: %w", err))
+	var requestBody models.UpdateCategoryRequest
+	if err := json.NewDecoder(request.Body).Decode(&requestBody); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", errJsonDecode, err))
 		return
 	}
 
-	responseBody := TokenResponse{
-		Token: token,
+	category, err := r.categoriesService.UpdateCategory(request.Context(), name, requestBody)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("UpdateCategory: %w", err))
+		return
 	}
 
-	buf, err := json.Marshal(responseBody)
+	buf, err := json.Marshal(category)
 	if err != nil {
 		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
 		return
@@ -407,16 +825,580 @@ func (r *Router) createTeacherToken(writer http.ResponseWriter, request *http.Re
 	r.sendResponse(writer, request, http.StatusOK, buf)
 }
 
-func getPaginationParameter(request *http.Request, parameterName string, defaultValue int) (int, error) {
-	parameter := request.URL.Query().Get(parameterName)
+func (r *Router) reorderCategories(writer http.ResponseWriter, request *http.Request) {
+	var requestBody models.ReorderCategoriesRequest
+	if err := json.NewDecoder(request.Body).Decode(&requestBody); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", errJsonDecode, err))
+		return
+	}
 
-	if parameter == "" {
-		return defaultValue, nil
+	if err := r.categoriesService.ReorderCategories(request.Context(), requestBody); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("ReorderCategories: %w", err))
+		return
 	}
 
-	value, err := strconv.Atoi(parameter)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Router) getRules(writer http.ResponseWriter, request *http.Request) {
+	rules, err := r.rulesService.GetRules(request.Context())
 	if err != nil {
-		return 0, fmt.Errorf("%w %s: %w", errInvalidPaginationParameter, parameterName, err)
+		r.sendErrorResponse(writer, request, fmt.Errorf("GetRules: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(rules)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) createRule(writer http.ResponseWriter, request *http.Request) {
+	var requestBody models.Rule
+	if err := json.NewDecoder(request.Body).Decode(&requestBody); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", errJsonDecode, err))
+		return
+	}
+
+	rule, err := r.rulesService.CreateRule(request.Context(), requestBody)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("CreateRule: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(rule)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusCreated, buf)
+}
+
+func (r *Router) updateRule(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+	if id == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
+		return
+	}
+
+	var requestBody models.Rule
+	if err := json.NewDecoder(request.Body).Decode(&requestBody); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", errJsonDecode, err))
+		return
+	}
+
+	rule, err := r.rulesService.UpdateRule(request.Context(), id, requestBody)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("UpdateRule: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(rule)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) deleteRule(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+	if id == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
+		return
+	}
+
+	if err := r.rulesService.DeleteRule(request.Context(), id); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("DeleteRule: %w", err))
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Router) applyRules(writer http.ResponseWriter, request *http.Request) {
+	dryRun, err := getBoolParameter(request.URL.Query().Get("dryRun"), "dryRun", true)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
+		return
+	}
+
+	result, err := r.transactionsService.ApplyRules(request.Context(), dryRun)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("ApplyRules: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(result)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) getPayees(writer http.ResponseWriter, request *http.Request) {
+	payees, err := r.payeesService.GetPayees(request.Context())
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("GetPayees: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(payees)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) createPayee(writer http.ResponseWriter, request *http.Request) {
+	var requestBody models.Payee
+	if err := json.NewDecoder(request.Body).Decode(&requestBody); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", errJsonDecode, err))
+		return
+	}
+
+	payee, err := r.payeesService.CreatePayee(request.Context(), requestBody)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("CreatePayee: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(payee)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusCreated, buf)
+}
+
+func (r *Router) updatePayee(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+	if id == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
+		return
+	}
+
+	var requestBody models.Payee
+	if err := json.NewDecoder(request.Body).Decode(&requestBody); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", errJsonDecode, err))
+		return
+	}
+
+	payee, err := r.payeesService.UpdatePayee(request.Context(), id, requestBody)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("UpdatePayee: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(payee)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) deletePayee(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+	if id == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
+		return
+	}
+
+	if err := r.payeesService.DeletePayee(request.Context(), id); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("DeletePayee: %w", err))
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Router) getPayeeStatistics(writer http.ResponseWriter, request *http.Request) {
+	var fromDate, toDate time.Time
+	var err error
+
+	if fromStr := request.URL.Query().Get("from"); fromStr != "" {
+		if fromDate, err = time.Parse("2006-01-02", fromStr); err != nil {
+			r.sendErrorResponse(writer, request, fmt.Errorf("%w: invalid from date format: %w", models.ErrBadRequest, err))
+			return
+		}
+	}
+
+	if toStr := request.URL.Query().Get("to"); toStr != "" {
+		if toDate, err = time.Parse("2006-01-02", toStr); err != nil {
+			r.sendErrorResponse(writer, request, fmt.Errorf("%w: invalid to date format: %w", models.ErrBadRequest, err))
+			return
+		}
+	}
+
+	statistics, err := r.statisticsService.GetPayeeStatistics(request.Context(), fromDate, toDate)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("GetPayeeStatistics: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(statistics)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) importCSV(writer http.ResponseWriter, request *http.Request) {
+	request.Body = http.MaxBytesReader(writer, request.Body, r.maxRequestBodySize)
+
+	file, _, err := request.FormFile("file")
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: can't read file: %w", models.ErrBadRequest, err))
+		return
+	}
+	defer file.Close()
+
+	options := models.CSVImportOptions{
+		DateFormat:      request.FormValue("dateFormat"),
+		DateColumn:      request.FormValue("dateColumn"),
+		AmountColumn:    request.FormValue("amountColumn"),
+		TitleColumn:     request.FormValue("titleColumn"),
+		CategoryColumn:  request.FormValue("categoryColumn"),
+		DefaultCategory: request.FormValue("defaultCategory"),
+	}
+
+	if delimiter := request.FormValue("delimiter"); delimiter != "" {
+		runes := []rune(delimiter)
+		if delimiter == `\t` {
+			runes = []rune{'\t'}
+		}
+		if len(runes) != 1 {
+			r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errInvalidDelimiter))
+			return
+		}
+		options.Delimiter = runes[0]
+	}
+
+	if options.HasHeader, err = getBoolParameter(request.FormValue("hasHeader"), "hasHeader", true); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
+		return
+	}
+
+	importOptions, err := getImportOptions(request)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
+		return
+	}
+
+	result, err := r.importService.ImportCSV(request.Context(), file, options, importOptions)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("ImportCSV: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(result)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) importStatement(writer http.ResponseWriter, request *http.Request) {
+	request.Body = http.MaxBytesReader(writer, request.Body, r.maxRequestBodySize)
+
+	file, _, err := request.FormFile("file")
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: can't read file: %w", models.ErrBadRequest, err))
+		return
+	}
+	defer file.Close()
+
+	options, err := getImportOptions(request)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, err))
+		return
+	}
+
+	format := strings.ToLower(request.PathValue("format"))
+
+	result, err := r.importService.ImportStatement(request.Context(), format, file, options)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("ImportStatement: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(result)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+// exportContentTypes типы содержимого файлов выгрузки
+var exportContentTypes = map[string]string{
+	models.ExportFormatCSV:  "text/csv; charset=utf-8",
+	models.ExportFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	models.ExportFormatJSON: "application/json",
+}
+
+// exportWriter выставляет заголовки файла перед первой записью, чтобы
+// ошибки параметров выгрузки отправлялись обычным JSON-ответом
+type exportWriter struct {
+	http.ResponseWriter
+	contentType string
+	fileName    string
+	started     bool
+}
+
+func (ew *exportWriter) Write(body []byte) (int, error) {
+	if !ew.started {
+		ew.started = true
+		ew.Header().Set("Content-Type", ew.contentType)
+		ew.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, ew.fileName))
+		ew.WriteHeader(http.StatusOK)
+	}
+
+	return ew.ResponseWriter.Write(body)
+}
+
+func (r *Router) export(writer http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get("format")
+	if format == "" {
+		format = models.ExportFormatCSV
+	}
+
+	contentType, exists := exportContentTypes[format]
+	if !exists {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errInvalidExportFormat))
+		return
+	}
+
+	categories := request.URL.Query()["category"]
+	if len(categories) == 1 && categories[0] == "" {
+		categories = []string{}
+	}
+
+	var fromDate, toDate time.Time
+	var err error
+
+	if fromStr := request.URL.Query().Get("from"); fromStr != "" {
+		if fromDate, err = time.Parse("2006-01-02", fromStr); err != nil {
+			r.sendErrorResponse(writer, request, fmt.Errorf("%w: invalid from date format: %w", models.ErrBadRequest, err))
+			return
+		}
+	}
+
+	if toStr := request.URL.Query().Get("to"); toStr != "" {
+		if toDate, err = time.Parse("2006-01-02", toStr); err != nil {
+			r.sendErrorResponse(writer, request, fmt.Errorf("%w: invalid to date format: %w", models.ErrBadRequest, err))
+			return
+		}
+	}
+
+	// Язык берем из параметра lang, иначе из заголовка Accept-Language
+	locale := request.URL.Query().Get("lang")
+	if locale == "" {
+		locale = models.LocaleRU
+		if strings.HasPrefix(strings.ToLower(request.Header.Get("Accept-Language")), models.LocaleEN) {
+			locale = models.LocaleEN
+		}
+	}
+
+	options := models.ExportOptions{
+		Format: format,
+		Locale: locale,
+		Filter: models.TransactionsFilter{
+			Categories: categories,
+			FromDate:   fromDate,
+			ToDate:     toDate,
+		},
+	}
+
+	fileWriter := &exportWriter{
+		ResponseWriter: writer,
+		contentType:    contentType,
+		fileName:       "transactions." + format,
+	}
+
+	if err := r.exportService.Export(request.Context(), fileWriter, options); err != nil {
+		if !fileWriter.started {
+			r.sendErrorResponse(writer, request, fmt.Errorf("Export: %w", err))
+			return
+		}
+
+		// Заголовки уже отправлены, клиент получит оборванный файл
+		r.logger.With(
+			"module", "api",
+			"request_url", request.Method+": "+request.URL.Path,
+		).Errorf("Export interrupted: %v", err)
+	}
+}
+
+func (r *Router) getMonthlyReport(writer http.ResponseWriter, request *http.Request) {
+	month := time.Now()
+	if monthStr := request.URL.Query().Get("month"); monthStr != "" {
+		var err error
+		if month, err = time.Parse("2006-01", monthStr); err != nil {
+			r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errInvalidMonthParameter))
+			return
+		}
+	}
+
+	report, err := r.reportService.GetMonthlyReport(request.Context(), month)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("GetMonthlyReport: %w", err))
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/pdf")
+	writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="report-%s.pdf"`, month.Format("2006-01")))
+	writer.WriteHeader(http.StatusOK)
+
+	if _, err := writer.Write(report); err != nil {
+		r.logger.With(
+			"module", "api",
+			"request_url", request.Method+": "+request.URL.Path,
+		).Errorf("Error sending report: %v", err)
+	}
+}
+
+func (r *Router) exportAccount(writer http.ResponseWriter, request *http.Request) {
+	archive := r.accountService.Export(request.Context())
+
+	buf, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%s.json"`, archive.ExportedAt.Format("2006-01-02")))
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) importAccount(writer http.ResponseWriter, request *http.Request) {
+	request.Body = http.MaxBytesReader(writer, request.Body, r.maxRequestBodySize)
+
+	file, _, err := request.FormFile("file")
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: can't read file: %w", models.ErrBadRequest, err))
+		return
+	}
+	defer file.Close()
+
+	result, err := r.accountService.Import(request.Context(), file, strings.ToLower(request.FormValue("mode")))
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("ImportAccount: %w", err))
+		return
+	}
+
+	buf, err := json.Marshal(result)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) deleteAccount(writer http.ResponseWriter, request *http.Request) {
+	if err := r.accountService.DeleteAccount(request.Context()); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("DeleteAccount: %w", err))
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Router) deleteManagedAccount(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+	if id == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyID))
+		return
+	}
+
+	if err := r.accountService.DeleteManagedAccount(request.Context(), id); err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("DeleteManagedAccount: %w", err))
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Router) createToken(writer http.ResponseWriter, request *http.Request) {
+	name := request.URL.Query().Get("name")
+	if name == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyName))
+		return
+	}
+
+	token, err := r.tokenService.GenerateToken(request.Context(), name, false)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("CreateToken: %w", err))
+		return
+	}
+
+	responseBody := TokenResponse{
+		Token: token,
+	}
+
+	buf, err := json.Marshal(responseBody)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func (r *Router) createTeacherToken(writer http.ResponseWriter, request *http.Request) {
+	name := request.URL.Query().Get("name")
+	if name == "" {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrBadRequest, errEmptyName))
+		return
+	}
+
+	token, err := r.tokenService.GenerateToken(request.Context(), name, true)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("CreateToken: %w", err))
+		return
+	}
+
+	responseBody := TokenResponse{
+		Token: token,
+	}
+
+	buf, err := json.Marshal(responseBody)
+	if err != nil {
+		r.sendErrorResponse(writer, request, fmt.Errorf("%w: %w", models.ErrInternalServer, err))
+		return
+	}
+
+	r.sendResponse(writer, request, http.StatusOK, buf)
+}
+
+func getPaginationParameter(request *http.Request, parameterName string, defaultValue int) (int, error) {
+	parameter := request.URL.Query().Get(parameterName)
+
+	if parameter == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(parameter)
+	if err != nil {
+		return 0, fmt.Errorf("%w %s: %w", errInvalidPaginationParameter, parameterName, err)
 	}
 
 	if value <= 0 {
@@ -426,6 +1408,47 @@ func getPaginationParameter(request *http.Request, parameterName string, default
 	return value, nil
 }
 
+func getAmountParameter(request *http.Request, parameterName string) (*float64, error) {
+	parameter := request.URL.Query().Get(parameterName)
+
+	if parameter == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseFloat(strings.Replace(parameter, ",", ".", 1), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %w", errInvalidAmountParameter, parameterName, err)
+	}
+
+	return &value, nil
+}
+
+func getBoolParameter(parameter, parameterName string, defaultValue bool) (bool, error) {
+	if parameter == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.ParseBool(parameter)
+	if err != nil {
+		return false, fmt.Errorf("%w %s: %w", errInvalidBoolParameter, parameterName, err)
+	}
+
+	return value, nil
+}
+
+// getImportOptions читает общие параметры импорта из формы
+func getImportOptions(request *http.Request) (models.ImportOptions, error) {
+	dryRun, err := getBoolParameter(request.FormValue("dryRun"), "dryRun", false)
+	if err != nil {
+		return models.ImportOptions{}, err
+	}
+
+	return models.ImportOptions{
+		DryRun:     dryRun,
+		Duplicates: request.FormValue("duplicates"),
+	}, nil
+}
+
 func (r *Router) healthCheck(writer http.ResponseWriter, _ *http.Request) {
 	response := map[string]string{
 		"status": "ok",
@@ -21,8 +21,18 @@ type Application struct {
 	statisticsService            *service.StatisticsService
 	transactionsService          *service.TransactionsService
 	categoriesService            *service.CategoriesService
+	rulesService                 *service.RulesService
+	payeesService                *service.PayeesService
+	attachmentsService           *service.AttachmentsService
 	recurringTransactionsService *service.RecurringTransactionsService
+	trashPurgeService            *service.TrashPurgeService
 	backupService                *service.BackupService
+	importService                *service.ImportService
+	exportService                *service.ExportService
+	reportService                *service.ReportService
+	accountService               *service.AccountService
+	idempotencyService           *service.IdempotencyService
+	authMiddleware               *api.AuthMiddleware
 	logger                       *zap.SugaredLogger
 
 	errChan chan error
@@ -59,6 +69,16 @@ func (a *Application) Start(ctx context.Context) error {
 		a.recurringTransactionsService.Start(ctx)
 	})
 
+	// Запускаем очистку корзины от просроченных транзакций в отдельной горутине
+	a.wg.Go(func() {
+		a.trashPurgeService.Start(ctx)
+	})
+
+	// Запускаем очистку просроченных ключей идемпотентности в отдельной горутине
+	a.wg.Go(func() {
+		a.idempotencyService.Start(ctx)
+	})
+
 	// Приложение готово к работе
 	a.ready = true
 
@@ -139,10 +159,33 @@ func (a *Application) initLogger() error {
 func (a *Application) initServices() error {
 	// Инициализируем сервисы с данными из конфига
 	a.tokenService = service.NewTokenService(a.cfg.PrivateKey, a.cfg.CreatedTokensPath)
-	a.transactionsService = service.NewTransactionsService(a.cfg.InitialFinancialData.Transactions)
-	a.categoriesService = service.NewCategoriesService(a.cfg.InitialFinancialData.Categories)
-	a.statisticsService = service.NewStatisticsService(a.transactionsService)
+	a.categoriesService = service.NewCategoriesService(a.cfg.InitialFinancialData.Categories, a.cfg.BaseCategories)
+	a.rulesService = service.NewRulesService(a.cfg.InitialFinancialData.Rules)
+	a.payeesService = service.NewPayeesService(a.cfg.InitialFinancialData.Payees)
+	// Размер вложения ограничен тем же лимитом, что и тело запроса
+	a.attachmentsService = service.NewAttachmentsService(
+		a.cfg.AttachmentsPath,
+		int64(a.cfg.ServerOpts.MaxRequestBodySizeMb)<<20,
+		a.logger,
+	)
+	a.transactionsService = service.NewTransactionsService(
+		a.cfg.InitialFinancialData.Transactions,
+		a.categoriesService,
+		a.rulesService,
+		a.attachmentsService,
+		time.Duration(a.cfg.TrashRetentionDays)*24*time.Hour,
+	)
+	a.statisticsService = service.NewStatisticsService(a.transactionsService, a.categoriesService, a.payeesService)
 	a.recurringTransactionsService = service.NewRecurringTransactionsService(a.transactionsService, a.logger)
+	a.trashPurgeService = service.NewTrashPurgeService(a.transactionsService, a.logger)
+	a.importService = service.NewImportService(a.transactionsService, a.rulesService)
+	a.exportService = service.NewExportService(a.transactionsService)
+
+	var err error
+	if a.reportService, err = service.NewReportService(a.statisticsService, a.transactionsService, a.categoriesService); err != nil {
+		return fmt.Errorf("can't init report service: %w", err)
+	}
+	a.idempotencyService = service.NewIdempotencyService(time.Duration(a.cfg.IdempotencyKeyTTLHours)*time.Hour, a.logger)
 
 	// Инициализируем сервис бэкапа (каждые 24 часа)
 	a.backupService = service.NewBackupService(a.logger, "data", 24*time.Hour)
@@ -150,12 +193,27 @@ func (a *Application) initServices() error {
 	// Регистрируем все сервисы для бэкапа
 	a.backupService.RegisterBackupable(a.transactionsService)
 	a.backupService.RegisterBackupable(a.categoriesService)
+	a.backupService.RegisterBackupable(a.rulesService)
+	a.backupService.RegisterBackupable(a.payeesService)
+
+	// Отзыв токенов удаленных пользователей проверяется в auth middleware
+	a.authMiddleware = api.NewAuthMiddleware(a.cfg.PublicKey, a.logger, a.cfg.RevokedTokens)
+	a.accountService = service.NewAccountService(
+		a.transactionsService,
+		a.categoriesService,
+		a.rulesService,
+		a.payeesService,
+		[]service.UserDataDeleter{a.backupService, a.idempotencyService, a.attachmentsService},
+		a.tokenService,
+		a.authMiddleware,
+		a.cfg.DeletedUsersPath,
+	)
 
 	return nil
 }
 
 func (a *Application) initRouter(ctx context.Context) error {
-	authMiddleware := api.NewAuthMiddleware(a.cfg.PublicKey, a.logger, a.cfg.RevokedTokens).JWTAuth
+	authMiddleware := a.authMiddleware.JWTAuth
 	loggingMiddleware := api.NewLoggerMiddleware(a.logger).Middleware
 
 	router := api.NewRouter(
@@ -164,6 +222,13 @@ func (a *Application) initRouter(ctx context.Context) error {
 		a.statisticsService,
 		a.transactionsService,
 		a.categoriesService,
+		a.rulesService,
+		a.payeesService,
+		a.importService,
+		a.exportService,
+		a.reportService,
+		a.accountService,
+		a.idempotencyService,
 		authMiddleware,
 		loggingMiddleware,
 		a.logger,
@@ -21,8 +21,10 @@ import (
 )
 
 var (
-	errDecodePem            = errors.New("can't decode pem")
-	errKeyIsNotRsaPublicKey = errors.New("key is not RSA public key")
+	errDecodePem             = errors.New("can't decode pem")
+	errKeyIsNotRsaPublicKey  = errors.New("key is not RSA public key")
+	errInvalidBaseCategory   = errors.New("invalid base category")
+	errInvalidTrashRetention = errors.New("trash retention must be positive")
 )
 
 type Config struct {
@@ -35,11 +37,22 @@ type Config struct {
 
 	// Financial tracking data
 	InitialFinancialData models.FinancialData
+	// BaseCategories категории, доступные всем пользователям
+	BaseCategories []models.Category
 
 	ServerOpts        ServerOpts
 	FeedbacksPath     string
 	CreatedTokensPath string
-	Host              string
+	// DeletedUsersPath журнал удаленных пользователей: их токены отозваны, а данные не загружаются при старте
+	DeletedUsersPath string
+	// AttachmentsPath директория с файлами, прикрепленными к транзакциям
+	AttachmentsPath string
+	Host            string
+
+	// IdempotencyKeyTTLHours время хранения ответов на запросы с Idempotency-Key
+	IdempotencyKeyTTLHours int `env:"IDEMPOTENCY_KEY_TTL_HOURS"`
+	// TrashRetentionDays время хранения удаленных транзакций в корзине, не меньше одного дня
+	TrashRetentionDays int `env:"TRASH_RETENTION_DAYS"`
 }
 
 func GetConfig(logger *zap.SugaredLogger) (*Config, error) {
@@ -51,8 +64,12 @@ func GetConfig(logger *zap.SugaredLogger) (*Config, error) {
 			IdleTimeout:          60,
 			MaxRequestBodySizeMb: 1,
 		},
-		CreatedTokensPath: "data/created_tokens.csv",
-		Host:              "http://eats-pages.ddns.net/uploads/",
+		CreatedTokensPath:      "data/created_tokens.csv",
+		DeletedUsersPath:       "data/deleted_users.csv",
+		AttachmentsPath:        "data/attachments",
+		Host:                   "http://eats-pages.ddns.net/uploads/",
+		IdempotencyKeyTTLHours: 24,
+		TrashRetentionDays:     models.DefaultTrashRetentionDays,
 	}
 
 	// Загружаем заблокированные токены
@@ -73,6 +90,28 @@ func GetConfig(logger *zap.SugaredLogger) (*Config, error) {
 		cfg.InitialFinancialData = financialData
 	}
 
+	// Загружаем базовые категории
+	baseCategories, err := getBaseCategories("data/base_categories.json", logger)
+	if err != nil {
+		logger.Warnf("Can't load base categories from file, using defaults: %v", err)
+		cfg.BaseCategories = models.GetDefaultBaseCategories()
+	} else {
+		cfg.BaseCategories = baseCategories
+	}
+
+	// Данные удаленных пользователей не восстанавливаются из бэкапов, сделанных до удаления
+	deletedUsers, err := getDeletedUsers(cfg.DeletedUsersPath)
+	if err != nil {
+		logger.Warnf("Can't load deleted users from file: %v", err)
+	}
+	for _, userID := range deletedUsers {
+		delete(cfg.InitialFinancialData.Transactions, userID)
+		delete(cfg.InitialFinancialData.Categories, userID)
+		delete(cfg.InitialFinancialData.Rules, userID)
+		delete(cfg.InitialFinancialData.Payees, userID)
+		cfg.RevokedTokens = append(cfg.RevokedTokens, userID)
+	}
+
 	opts := env.Options{
 		FuncMap: map[reflect.Type]env.ParserFunc{
 			reflect.TypeOf(rsa.PublicKey{}):  ParsePubKey,
@@ -85,6 +124,11 @@ func GetConfig(logger *zap.SugaredLogger) (*Config, error) {
 		return nil, fmt.Errorf("env.ParseWithOptions: %w", err)
 	}
 
+	// При нулевом сроке очистка корзины сразу удалила бы все удаленные транзакции и их вложения
+	if cfg.TrashRetentionDays <= 0 {
+		return nil, fmt.Errorf("%w: TRASH_RETENTION_DAYS=%d", errInvalidTrashRetention, cfg.TrashRetentionDays)
+	}
+
 	return cfg, nil
 }
 
@@ -183,6 +227,57 @@ func getInitData[T loadable](filePath string, logger *zap.SugaredLogger) ([]T, e
 	return loadJSONFile[[]T](filePath, logger)
 }
 
+// getDeletedUsers возвращает ID пользователей из журнала удалений (deletedAt;userID;actorID;actorNickname)
+func getDeletedUsers(filePath string) ([]string, error) {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var userIDs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ";")
+		if len(fields) >= 2 && fields[1] != "" {
+			userIDs = append(userIDs, fields[1])
+		}
+	}
+
+	return userIDs, nil
+}
+
+// getBaseCategories загружает базовые категории и проверяет, что названия уникальны,
+// а категория доходов есть в списке: по ней статистика отличает доходы от расходов
+func getBaseCategories(filePath string, logger *zap.SugaredLogger) ([]models.Category, error) {
+	categories, err := loadJSONFile[[]models.Category](filePath, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{}, len(categories))
+	for _, category := range categories {
+		name := strings.ToLower(strings.TrimSpace(category.Name))
+		if name == "" {
+			return nil, fmt.Errorf("%w: empty name", errInvalidBaseCategory)
+		}
+		if category.Parent != "" {
+			return nil, fmt.Errorf("%w: %s: base categories cannot have parent", errInvalidBaseCategory, category.Name)
+		}
+		if _, exists := names[name]; exists {
+			return nil, fmt.Errorf("%w: duplicate name %s", errInvalidBaseCategory, category.Name)
+		}
+		names[name] = struct{}{}
+	}
+
+	if _, exists := names[strings.ToLower(models.IncomeCategory)]; !exists {
+		return nil, fmt.Errorf("%w: %s category is required", errInvalidBaseCategory, models.IncomeCategory)
+	}
+
+	return categories, nil
+}
+
 // getFinancialData загружает финансовые данные из файла
 func getFinancialData(filePath string, logger *zap.SugaredLogger) (models.FinancialData, error) {
 	return loadJSONFile[models.FinancialData](filePath, logger)
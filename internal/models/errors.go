@@ -8,4 +8,5 @@ var (
 	ErrNotFound       = errors.New("not found")
 	ErrUnauthorized   = errors.New("unauthorized")
 	ErrForbidden      = errors.New("forbidden")
+	ErrConflict       = errors.New("conflict")
 )
@@ -9,6 +9,24 @@ import (
 
 const DefaultPageSize = 20
 
+// Поля сортировки списка транзакций
+const (
+	SortByDate   = "date"
+	SortByAmount = "amount"
+	SortByTitle  = "title"
+)
+
+// Направления сортировки
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+const (
+	DefaultForecastMonths = 3
+	MaxForecastMonths     = 24
+)
+
 // Категория для доходов
 const IncomeCategory = "Доходы"
 
@@ -21,6 +39,14 @@ type AuthTokenClaims struct {
 
 type ContextClaimsKey struct{}
 
+// IssuedToken запись о выпущенном токене из журнала созданных токенов
+type IssuedToken struct {
+	Issuer    string
+	Nickname  string
+	ID        string
+	IsTeacher bool
+}
+
 func ClaimsFromContext(ctx context.Context) *AuthTokenClaims {
 	claims, _ := ctx.Value(ContextClaimsKey{}).(*AuthTokenClaims)
 	return claims
@@ -35,14 +61,129 @@ type Transaction struct {
 	Date           time.Time  `json:"date"`
 	NextAppearDate time.Time `json:"nextAppearDate,omitempty"`
 	RepeatTime     string     `json:"-"`
+	// Tags метки транзакции в нижнем регистре, отсортированные по алфавиту
+	Tags []string `json:"tags,omitempty"`
+	// Splits разбивка суммы по категориям, Category при этом - основная категория
+	Splits []TransactionSplit `json:"splits,omitempty"`
+	// Note произвольная заметка пользователя
+	Note string `json:"note,omitempty"`
+	// Attachments файлы, прикрепленные к транзакции, например фото чеков
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Receipt фискальные признаки чека, по QR-коду которого создана транзакция
+	Receipt *Receipt `json:"receipt,omitempty"`
+	// DeletedAt время удаления транзакции в корзину, у обычных транзакций не указывается
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+// Receipt фискальные признаки кассового чека, вместе они однозначно определяют чек
+type Receipt struct {
+	// FN номер фискального накопителя
+	FN string `json:"fn"`
+	// I номер фискального документа
+	I string `json:"i"`
+	// FP фискальный признак документа
+	FP string `json:"fp"`
+}
+
+// ReceiptRequest создание транзакции по строке из QR-кода чека. Необязательные поля
+// заменяют значения, заполненные по чеку.
+type ReceiptRequest struct {
+	// QR строка вида t=20260101T1200&s=523.00&fn=...&i=...&fp=...&n=1
+	QR       string   `json:"qr"`
+	Title    string   `json:"title,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Note     string   `json:"note,omitempty"`
+}
+
+// ReceiptResponse транзакция, созданная по чеку
+type ReceiptResponse struct {
+	// Created false, если чек уже сканировали и транзакция по нему существует
+	Created     bool        `json:"created"`
+	Transaction Transaction `json:"transaction"`
+}
+
+// Attachment описание прикрепленного к транзакции файла. Сам файл хранится на диске
+// и скачивается через API.
+type Attachment struct {
+	ID          string    `json:"id"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	UploadedAt  time.Time `json:"uploadedAt"`
+}
+
+// Ограничения заметок и вложений транзакций
+const (
+	MaxNoteLength             = 1000
+	MaxTransactionAttachments = 10
+)
+
+// TransactionSplit строка разбивки транзакции
+type TransactionSplit struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
 }
 
 type CreateTransactionRequest struct {
-	Amount     float64 `json:"amount"`
-	Title      string  `json:"title"`
-	Category   string  `json:"category"`
-	Date       string  `json:"date"`
-	RepeatTime string  `json:"repeatTime,omitempty"`
+	Amount     float64  `json:"amount"`
+	Title      string   `json:"title"`
+	Category   string   `json:"category"`
+	Date       string   `json:"date"`
+	RepeatTime string   `json:"repeatTime,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	// Splits строки разбивки, их суммы должны давать Amount. Category можно не указывать.
+	Splits []TransactionSplit `json:"splits,omitempty"`
+	Note   string             `json:"note,omitempty"`
+}
+
+// TransactionsFilter параметры фильтрации списка транзакций
+type TransactionsFilter struct {
+	Categories []string
+	FromDate   time.Time
+	ToDate     time.Time
+	// Query строка полнотекстового поиска по названию транзакции
+	Query     string
+	MinAmount *float64
+	MaxAmount *float64
+	Tags      []string
+	// TagsMatch any (по умолчанию) - транзакция отмечена хотя бы одним из тегов, all - всеми
+	TagsMatch string
+}
+
+// Теги транзакций
+const (
+	TagsMatchAny = "any"
+	TagsMatchAll = "all"
+
+	MaxTransactionTags = 20
+	MaxTagLength       = 50
+	DefaultTagsLimit   = 20
+)
+
+type TagUsage struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// DefaultSuggestionsLimit количество подсказок категорий по умолчанию
+const DefaultSuggestionsLimit = 3
+
+// CategorySuggestion категория, предложенная по истории транзакций пользователя
+type CategorySuggestion struct {
+	Category string `json:"category"`
+	// Probability оценка вероятности категории, сумма по всем категориям пользователя равна 1
+	Probability float64 `json:"probability"`
+}
+
+// TransactionsPagination параметры сортировки и пагинации списка транзакций.
+// Если указан Cursor, Page игнорируется, а сортировка берется из курсора.
+type TransactionsPagination struct {
+	Page     int
+	PageSize int
+	SortBy   string
+	Order    string
+	Cursor   string
 }
 
 type CreateTransactionResponse struct {
@@ -52,6 +193,8 @@ type CreateTransactionResponse struct {
 type TransactionsResponse struct {
 	CurrentPage int           `json:"currentPage"`
 	TotalPages  int           `json:"totalPages"`
+	TotalCount  int           `json:"totalCount"`
+	NextCursor  string        `json:"nextCursor,omitempty"`
 	Data        []Transaction `json:"data"`
 }
 
@@ -68,23 +211,408 @@ type SpendingCurveInfo struct {
 	Date            string  `json:"date"`
 }
 
+// TagStatistics доходы и расходы транзакций с тегом. Транзакция с несколькими
+// тегами учитывается в каждом из них.
+type TagStatistics struct {
+	Tag      string  `json:"tag"`
+	Income   float64 `json:"income"`
+	Expenses float64 `json:"expenses"`
+	Count    int     `json:"count"`
+}
+
+// CategoryStatistics расходы категории вместе с подкатегориями
+type CategoryStatistics struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	// OwnAmount расходы, записанные непосредственно в категорию, без подкатегорий
+	OwnAmount float64              `json:"ownAmount"`
+	Children  []CategoryStatistics `json:"children,omitempty"`
+}
+
+// DefaultTopMerchantsLimit количество получателей в разделе крупнейших в статистике
+const DefaultTopMerchantsLimit = 5
+
+// PayeeStatistics доходы и расходы транзакций получателя за период
+type PayeeStatistics struct {
+	// PayeeID ID получателя пользователя, пустой у получателей, распознанных только по названию
+	PayeeID  string  `json:"payeeId,omitempty"`
+	Name     string  `json:"name"`
+	Income   float64 `json:"income"`
+	Expenses float64 `json:"expenses"`
+	Count    int     `json:"count"`
+	// LastDate дата последней транзакции получателя
+	LastDate string `json:"lastDate"`
+	// Titles исходные названия транзакций, объединенные в получателя
+	Titles []string `json:"titles"`
+}
+
+// StatisticsResponse статистика за период. В ExpensesByCategory строки разбитых
+// транзакций учитываются в своих категориях, в CategoryBreakdown суммы подкатегорий
+// дополнительно входят в суммы родителей.
 type StatisticsResponse struct {
-	GeneralStatistics    GeneralStatistics   `json:"generalStatistics"`
-	BalanceChangesByDate map[string]float64  `json:"balanceChangesByDate"`
-	SpendingCurveInfo    []SpendingCurveInfo `json:"spendingCurveInfo"`
-	FromDate             string              `json:"fromDate"`
-	ToDate               string              `json:"toDate"`
+	GeneralStatistics    GeneralStatistics    `json:"generalStatistics"`
+	BalanceChangesByDate map[string]float64   `json:"balanceChangesByDate"`
+	SpendingCurveInfo    []SpendingCurveInfo  `json:"spendingCurveInfo"`
+	ExpensesByCategory   map[string]float64   `json:"expensesByCategory"`
+	CategoryBreakdown    []CategoryStatistics `json:"categoryBreakdown"`
+	TagTotals            []TagStatistics      `json:"tagTotals"`
+	// TopMerchants получатели с наибольшими расходами за период
+	TopMerchants []PayeeStatistics `json:"topMerchants"`
+	FromDate     string            `json:"fromDate"`
+	ToDate       string            `json:"toDate"`
+}
+
+// Forecast models
+type ForecastPoint struct {
+	Date                  string  `json:"date"`
+	ScheduledIncome       float64 `json:"scheduledIncome"`
+	ScheduledExpenses     float64 `json:"scheduledExpenses"`
+	DiscretionaryIncome   float64 `json:"discretionaryIncome"`
+	DiscretionaryExpenses float64 `json:"discretionaryExpenses"`
+	Expected              float64 `json:"expected"`
+	Optimistic            float64 `json:"optimistic"`
+	Pessimistic           float64 `json:"pessimistic"`
+}
+
+type ForecastResponse struct {
+	StartBalance float64         `json:"startBalance"`
+	FromDate     string          `json:"fromDate"`
+	Months       int             `json:"months"`
+	Points       []ForecastPoint `json:"points"`
+}
+
+// Import models
+type CSVImportOptions struct {
+	// Delimiter разделитель столбцов
+	Delimiter rune
+	// DateFormat формат даты, например "DD.MM.YYYY"
+	DateFormat string
+	HasHeader  bool
+	// Столбцы задаются названием из заголовка или номером, начиная с 1
+	DateColumn     string
+	AmountColumn   string
+	TitleColumn    string
+	CategoryColumn string
+	// DefaultCategory используется, если категория в строке не указана
+	DefaultCategory string
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// Обработка дубликатов при импорте
+const (
+	// DuplicatesSkip дубликаты существующих транзакций не импортируются
+	DuplicatesSkip = "skip"
+	// DuplicatesFlag дубликаты импортируются и помечаются в результате
+	DuplicatesFlag = "flag"
+)
+
+type ImportOptions struct {
+	DryRun bool
+	// Duplicates режим обработки дубликатов: skip (по умолчанию) или flag
+	Duplicates string
+}
+
+type ImportedTransaction struct {
+	Row         int                      `json:"row"`
+	ID          string                   `json:"id,omitempty"`
+	Transaction CreateTransactionRequest `json:"transaction"`
+	// DuplicateOf ID существующей транзакции, дубликатом которой считается строка
+	DuplicateOf string `json:"duplicateOf,omitempty"`
+	Skipped     bool   `json:"skipped,omitempty"`
+}
+
+type ImportResult struct {
+	DryRun       bool                  `json:"dryRun"`
+	Total        int                   `json:"total"`
+	Imported     int                   `json:"imported"`
+	Failed       int                   `json:"failed"`
+	Duplicates   int                   `json:"duplicates"`
+	Skipped      int                   `json:"skipped"`
+	Transactions []ImportedTransaction `json:"transactions"`
+	Errors       []ImportRowError      `json:"errors"`
+}
+
+// Batch models
+// Операции пакетного изменения транзакций
+const (
+	BatchCreate       = "create"
+	BatchUpdate       = "update"
+	BatchDelete       = "delete"
+	BatchRecategorize = "recategorize"
+
+	MaxBatchOperations = 100
+	// MaxBatchItems ограничивает число транзакций, которые пакет меняет под одной блокировкой
+	MaxBatchItems = 1000
+)
+
+// Статусы элементов результата пакетной операции
+const (
+	BatchStatusCreated       = "created"
+	BatchStatusUpdated       = "updated"
+	BatchStatusDeleted       = "deleted"
+	BatchStatusRecategorized = "recategorized"
+	BatchStatusFailed        = "failed"
+)
+
+// BatchRequest операции над транзакциями, которые применяются вместе или не применяются совсем
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation одна операция пакета. Транзакции для update, delete и recategorize
+// выбираются списком IDs или фильтром Filter - указывается что-то одно.
+type BatchOperation struct {
+	Op     string       `json:"op"`
+	IDs    []string     `json:"ids,omitempty"`
+	Filter *BatchFilter `json:"filter,omitempty"`
+	// Transaction новая транзакция для create
+	Transaction *CreateTransactionRequest `json:"transaction,omitempty"`
+	// Update изменяемые поля для update
+	Update *TransactionUpdate `json:"update,omitempty"`
+	// Category новая категория для recategorize, разбивка транзакции при этом снимается
+	Category string `json:"category,omitempty"`
+}
+
+// BatchFilter фильтр транзакций с теми же параметрами, что у списка транзакций
+type BatchFilter struct {
+	Categories []string `json:"category,omitempty"`
+	From       string   `json:"from,omitempty"`
+	To         string   `json:"to,omitempty"`
+	Query      string   `json:"q,omitempty"`
+	MinAmount  *float64 `json:"minAmount,omitempty"`
+	MaxAmount  *float64 `json:"maxAmount,omitempty"`
+	Tags       []string `json:"tag,omitempty"`
+	TagsMatch  string   `json:"tagMatch,omitempty"`
+}
+
+// TransactionUpdate изменяемые поля транзакции, отсутствующие поля не меняются
+type TransactionUpdate struct {
+	Amount   *float64  `json:"amount,omitempty"`
+	Title    *string   `json:"title,omitempty"`
+	Category *string   `json:"category,omitempty"`
+	Date     *string   `json:"date,omitempty"`
+	Tags     *[]string `json:"tags,omitempty"`
+	Note     *string   `json:"note,omitempty"`
+}
+
+// BatchItemResult результат операции пакета для одной транзакции
+type BatchItemResult struct {
+	// Operation номер операции в запросе, начиная с 0
+	Operation int    `json:"operation"`
+	ID        string `json:"id,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BatchResult результат пакета. Если хотя бы один элемент не выполнен, Applied равен false
+// и ни одно изменение пакета не сохраняется.
+type BatchResult struct {
+	Applied bool              `json:"applied"`
+	Failed  int               `json:"failed"`
+	Results []BatchItemResult `json:"results"`
+}
+
+// Trash models
+// DefaultTrashRetentionDays сколько дней удаленные транзакции хранятся в корзине
+const DefaultTrashRetentionDays = 30
+
+// TrashResponse содержимое корзины пользователя
+type TrashResponse struct {
+	// RetentionDays через сколько дней после удаления транзакция удаляется навсегда
+	RetentionDays int           `json:"retentionDays"`
+	Transactions  []Transaction `json:"transactions"`
+}
+
+// Duplicate models
+type DuplicateGroup struct {
+	// Fingerprint общий отпечаток транзакций группы: дата, сумма и нормализованное название
+	Fingerprint  string        `json:"fingerprint"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+type DuplicatesResponse struct {
+	Groups []DuplicateGroup `json:"groups"`
+}
+
+// Export models
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+	ExportFormatJSON = "json"
+)
+
+const (
+	LocaleRU = "ru"
+	LocaleEN = "en"
+)
+
+type ExportOptions struct {
+	Format string
+	// Locale язык заголовков и формат чисел и дат: ru (по умолчанию) или en
+	Locale string
+	Filter TransactionsFilter
+}
+
+// Idempotency models
+// IdempotentResponse сохраненный ответ на запрос с ключом идемпотентности
+type IdempotentResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Account archive models
+// AccountArchiveVersion версия схемы архива данных аккаунта. Увеличивается при
+// несовместимых изменениях формата; архивы других версий не импортируются.
+const AccountArchiveVersion = 1
+
+// Режимы импорта архива аккаунта
+const (
+	// AccountImportMerge добавляет данные архива к существующим, транзакции с тем же ID заменяются
+	AccountImportMerge = "merge"
+	// AccountImportReplace удаляет данные пользователя перед загрузкой архива
+	AccountImportReplace = "replace"
+)
+
+// AccountArchive все данные пользователя в формате бэкапа сервисов
+type AccountArchive struct {
+	SchemaVersion int                    `json:"schemaVersion"`
+	ExportedAt    time.Time              `json:"exportedAt"`
+	UserID        string                 `json:"userId"`
+	Transactions  map[string]Transaction `json:"transactions"` // transactionID -> transaction
+	// Recurrence правила повторения транзакций, в бэкапе транзакций их нет
+	Recurrence map[string]string `json:"recurrence"` // transactionID -> repeatTime
+	Categories []Category        `json:"categories"`
+	Rules      []Rule            `json:"rules,omitempty"`
+	Payees     []Payee           `json:"payees,omitempty"`
+}
+
+type AccountImportResult struct {
+	Mode         string `json:"mode"`
+	Transactions int    `json:"transactions"`
+	Categories   int    `json:"categories"`
+	Rules        int    `json:"rules"`
+	Payees       int    `json:"payees"`
+
+	// MissingAttachments вложения из архива, файлов которых нет на этом сервере; они не загружаются
+	MissingAttachments int `json:"missingAttachments"`
 }
 
 // Category models
+// Типы категорий: подсказка, доходом или расходом по умолчанию считается новая транзакция
+const (
+	CategoryTypeExpense = "expense"
+	CategoryTypeIncome  = "income"
+)
+
 type Category struct {
 	Name string `json:"name"`
+	// Base исходное название базовой категории, пустое у пользовательских категорий.
+	// Транзакции ссылаются на базовую категорию по нему, поэтому переименование их не ломает.
+	Base string `json:"base,omitempty"`
+	// Parent название родительской категории, пустое у корневых категорий
+	Parent string `json:"parent,omitempty"`
+	// Icon ключ иконки в приложении
+	Icon string `json:"icon,omitempty"`
+	// Color цвет в формате #RRGGBB
+	Color string `json:"color,omitempty"`
+	Type  string `json:"type,omitempty"`
+	// Archived архивная категория скрыта из списка для выбора, но остается у старых транзакций
+	Archived bool `json:"archived,omitempty"`
+	// SortOrder порядок среди категорий с тем же родителем, меньшие значения первыми
+	SortOrder int `json:"sortOrder"`
+	// Children подкатегории, заполняются только в ответе со списком категорий
+	Children []Category `json:"children,omitempty"`
+}
+
+// UpdateCategoryRequest изменение оформления категории, пустые поля не меняются
+type UpdateCategoryRequest struct {
+	// Name новое название, переименовать можно только базовую категорию
+	Name     *string `json:"name,omitempty"`
+	Icon     *string `json:"icon,omitempty"`
+	Color    *string `json:"color,omitempty"`
+	Type     *string `json:"type,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+}
+
+// ReorderCategoriesRequest новый порядок категорий пользователя с общим родителем
+type ReorderCategoriesRequest struct {
+	Parent string   `json:"parent,omitempty"`
+	Names  []string `json:"names"`
+}
+
+// Rule models
+// Rule правило автоматической категоризации. Условия проверяются вместе, пустые условия
+// не проверяются. Применяется первое подходящее правило из списка пользователя.
+type Rule struct {
+	ID string `json:"id"`
+	// TitleContains подстрока названия без учета регистра
+	TitleContains string `json:"titleContains,omitempty"`
+	// TitleRegex регулярное выражение для названия в синтаксисе Go
+	TitleRegex string   `json:"titleRegex,omitempty"`
+	MinAmount  *float64 `json:"minAmount,omitempty"`
+	MaxAmount  *float64 `json:"maxAmount,omitempty"`
+	// Weekdays дни недели даты транзакции: mon, tue, wed, thu, fri, sat, sun
+	Weekdays []string `json:"weekdays,omitempty"`
+
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// Rename новое название транзакции
+	Rename string `json:"rename,omitempty"`
+}
+
+// RuleChange изменение транзакции при повторном применении правил
+type RuleChange struct {
+	TransactionID string   `json:"transactionId"`
+	RuleID        string   `json:"ruleId"`
+	Title         string   `json:"title"`
+	NewTitle      string   `json:"newTitle"`
+	Category      string   `json:"category"`
+	NewCategory   string   `json:"newCategory"`
+	Tags          []string `json:"tags,omitempty"`
+	NewTags       []string `json:"newTags,omitempty"`
+}
+
+// ApplyRulesResult результат применения правил к сохраненным транзакциям
+type ApplyRulesResult struct {
+	DryRun  bool         `json:"dryRun"`
+	Changed int          `json:"changed"`
+	Changes []RuleChange `json:"changes"`
+}
+
+// Payee models
+// Payee получатель платежа пользователя. Транзакция относится к получателю, если ее
+// нормализованное название начинается с нормализованного названия или одного из псевдонимов.
+type Payee struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
 }
 
 // FinancialData структура для хранения и загрузки данных финансового трекинга
 type FinancialData struct {
-	Transactions map[string]map[string]Transaction `json:"transactions"` // userID -> transactionID -> transaction
-	Categories   map[string][]Category             `json:"categories"`   // userID -> categories
+	Transactions map[string]map[string]Transaction `json:"transactions"`     // userID -> transactionID -> transaction
+	Categories   map[string][]Category             `json:"categories"`       // userID -> categories
+	Rules        map[string][]Rule                 `json:"rules,omitempty"`  // userID -> rules
+	Payees       map[string][]Payee                `json:"payees,omitempty"` // userID -> payees
+}
+
+// GetDefaultBaseCategories возвращает базовые категории, если они не заданы в конфигурации
+func GetDefaultBaseCategories() []Category {
+	return []Category{
+		{Name: "Еда", Icon: "food", Color: "#F59E0B", Type: CategoryTypeExpense},
+		{Name: "Транспорт", Icon: "transport", Color: "#3B82F6", Type: CategoryTypeExpense},
+		{Name: "Развлечения", Icon: "entertainment", Color: "#8B5CF6", Type: CategoryTypeExpense},
+		{Name: "Здоровье", Icon: "health", Color: "#EF4444", Type: CategoryTypeExpense},
+		{Name: "Одежда", Icon: "clothes", Color: "#EC4899", Type: CategoryTypeExpense},
+		{Name: IncomeCategory, Icon: "income", Color: "#10B981", Type: CategoryTypeIncome},
+		{Name: "Образование", Icon: "education", Color: "#6366F1", Type: CategoryTypeExpense},
+		{Name: "Подарки", Icon: "gifts", Color: "#F97316", Type: CategoryTypeExpense},
+		{Name: "Прочее", Icon: "other", Color: "#6B7280", Type: CategoryTypeExpense},
+	}
 }
 
 // GetDefaultFinancialData возвращает структуру с пустыми данными
@@ -92,5 +620,7 @@ func GetDefaultFinancialData() FinancialData {
 	return FinancialData{
 		Transactions: make(map[string]map[string]Transaction),
 		Categories:   make(map[string][]Category),
+		Rules:        make(map[string][]Rule),
+		Payees:       make(map[string][]Payee),
 	}
 }
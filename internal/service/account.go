@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errUnsupportedArchiveVersion = errors.New("unsupported archive schema version")
+	errUnknownAccountImportMode  = errors.New("unknown import mode")
+)
+
+type AccountTransactionsStore interface {
+	ExportUserTransactions(ctx context.Context) (map[string]models.Transaction, map[string]string)
+	ImportUserTransactions(ctx context.Context, transactions map[string]models.Transaction, recurrence map[string]string, replace bool) (int, int, error)
+}
+
+type AccountCategoriesStore interface {
+	ExportUserCategories(ctx context.Context) []models.Category
+	ImportUserCategories(ctx context.Context, categories []models.Category, replace bool) (int, error)
+}
+
+type AccountRulesStore interface {
+	ExportUserRules(ctx context.Context) []models.Rule
+	ImportUserRules(ctx context.Context, rules []models.Rule, replace bool) (int, error)
+}
+
+type AccountPayeesStore interface {
+	ExportUserPayees(ctx context.Context) []models.Payee
+	ImportUserPayees(ctx context.Context, payees []models.Payee, replace bool) (int, error)
+}
+
+// UserDataDeleter хранилище данных, из которого можно удалить пользователя
+type UserDataDeleter interface {
+	DeleteUserData(userID string)
+}
+
+type TokenRevoker interface {
+	Revoke(id string)
+}
+
+type IssuedTokensProvider interface {
+	GetIssuedToken(id string) (*models.IssuedToken, error)
+}
+
+// AccountService сервис переноса и удаления всех данных пользователя
+type AccountService struct {
+	transactionsService AccountTransactionsStore
+	categoriesService   AccountCategoriesStore
+	rulesService        AccountRulesStore
+	payeesService       AccountPayeesStore
+	userData            []UserDataDeleter
+	tokens              IssuedTokensProvider
+	revoker             TokenRevoker
+	// deletedUsersPath журнал удалений, по нему данные пользователя не восстанавливаются из старых бэкапов
+	deletedUsersPath string
+	deleteMux        sync.Mutex
+}
+
+// NewAccountService создает новый сервис данных аккаунта
+func NewAccountService(
+	transactionsService AccountTransactionsStore,
+	categoriesService AccountCategoriesStore,
+	rulesService AccountRulesStore,
+	payeesService AccountPayeesStore,
+	userData []UserDataDeleter,
+	tokens IssuedTokensProvider,
+	revoker TokenRevoker,
+	deletedUsersPath string,
+) *AccountService {
+	return &AccountService{
+		transactionsService: transactionsService,
+		categoriesService:   categoriesService,
+		rulesService:        rulesService,
+		payeesService:       payeesService,
+		userData:            userData,
+		tokens:              tokens,
+		revoker:             revoker,
+		deletedUsersPath:    deletedUsersPath,
+	}
+}
+
+// Export собирает архив со всеми данными пользователя
+func (as *AccountService) Export(ctx context.Context) *models.AccountArchive {
+	transactions, recurrence := as.transactionsService.ExportUserTransactions(ctx)
+
+	return &models.AccountArchive{
+		SchemaVersion: models.AccountArchiveVersion,
+		ExportedAt:    time.Now().UTC(),
+		UserID:        models.ClaimsFromContext(ctx).ID,
+		Transactions:  transactions,
+		Recurrence:    recurrence,
+		Categories:    as.categoriesService.ExportUserCategories(ctx),
+		Rules:         as.rulesService.ExportUserRules(ctx),
+		Payees:        as.payeesService.ExportUserPayees(ctx),
+	}
+}
+
+// Import загружает архив в данные текущего пользователя в режиме merge (по умолчанию) или replace.
+// ID пользователя из архива не используется, поэтому архив можно загрузить в другой аккаунт.
+func (as *AccountService) Import(ctx context.Context, reader io.Reader, mode string) (*models.AccountImportResult, error) {
+	if mode == "" {
+		mode = models.AccountImportMerge
+	}
+	if mode != models.AccountImportMerge && mode != models.AccountImportReplace {
+		return nil, fmt.Errorf("%w: %w: %s, must be one of: merge, replace", models.ErrBadRequest, errUnknownAccountImportMode, mode)
+	}
+
+	var archive models.AccountArchive
+	if err := json.NewDecoder(reader).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("%w: can't parse archive: %w", models.ErrBadRequest, err)
+	}
+
+	if archive.SchemaVersion != models.AccountArchiveVersion {
+		return nil, fmt.Errorf("%w: %w: %d, expected %d",
+			models.ErrBadRequest, errUnsupportedArchiveVersion, archive.SchemaVersion, models.AccountArchiveVersion)
+	}
+
+	// Категории проверяем заранее, чтобы ошибка в них не оставила импорт транзакций без категорий
+	if err := validateImportedCategories(archive.Categories); err != nil {
+		return nil, err
+	}
+
+	// Правила проверяются целиком до изменения данных
+	for _, rule := range archive.Rules {
+		if _, err := compileRule(rule); err != nil {
+			return nil, fmt.Errorf("%w: rule %s: %w", models.ErrBadRequest, rule.ID, err)
+		}
+	}
+
+	for _, payee := range archive.Payees {
+		if _, err := normalizePayeeRequest(payee); err != nil {
+			return nil, fmt.Errorf("%w: payee %s: %w", models.ErrBadRequest, payee.ID, err)
+		}
+	}
+
+	replace := mode == models.AccountImportReplace
+
+	transactions, missingAttachments, err := as.transactionsService.ImportUserTransactions(ctx, archive.Transactions, archive.Recurrence, replace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import transactions: %w", err)
+	}
+
+	categories, err := as.categoriesService.ImportUserCategories(ctx, archive.Categories, replace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import categories: %w", err)
+	}
+
+	rules, err := as.rulesService.ImportUserRules(ctx, archive.Rules, replace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import rules: %w", err)
+	}
+
+	payees, err := as.payeesService.ImportUserPayees(ctx, archive.Payees, replace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import payees: %w", err)
+	}
+
+	return &models.AccountImportResult{
+		Mode:               mode,
+		Transactions:       transactions,
+		Categories:         categories,
+		Rules:              rules,
+		Payees:             payees,
+		MissingAttachments: missingAttachments,
+	}, nil
+}
+
+// DeleteAccount удаляет все данные текущего пользователя и отзывает его токен
+func (as *AccountService) DeleteAccount(ctx context.Context) error {
+	return as.deleteUser(ctx, models.ClaimsFromContext(ctx).ID)
+}
+
+// DeleteManagedAccount удаляет пользователя, токен которого выпустил текущий преподаватель
+func (as *AccountService) DeleteManagedAccount(ctx context.Context, userID string) error {
+	claims := models.ClaimsFromContext(ctx)
+	if !claims.IsTeacher {
+		return fmt.Errorf("%w: only teacher can delete other users", models.ErrForbidden)
+	}
+
+	token, err := as.tokens.GetIssuedToken(userID)
+	if err != nil {
+		return err
+	}
+
+	if token.Issuer != claims.Nickname {
+		return fmt.Errorf("%w: user %s is not managed by %s", models.ErrForbidden, userID, claims.Nickname)
+	}
+
+	return as.deleteUser(ctx, userID)
+}
+
+// deleteUser записывает удаление в журнал, отзывает токен и удаляет данные пользователя.
+// Запись в журнал идет первой, чтобы после сбоя данные не вернулись из бэкапа.
+func (as *AccountService) deleteUser(ctx context.Context, userID string) error {
+	actor := models.ClaimsFromContext(ctx)
+
+	as.deleteMux.Lock()
+	defer as.deleteMux.Unlock()
+
+	record := fmt.Sprintf("%s;%s;%s;%s\n", time.Now().UTC().Format(time.RFC3339), userID, actor.ID, actor.Nickname)
+	if err := AppendFile(as.deletedUsersPath, []byte(record), 0600); err != nil {
+		return fmt.Errorf("failed to write deletion record: %w", err)
+	}
+
+	as.revoker.Revoke(userID)
+
+	for _, userData := range as.userData {
+		userData.DeleteUserData(userID)
+	}
+
+	return nil
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errAttachmentTooLarge        = errors.New("attachment is too large")
+	errUnsupportedAttachmentType = errors.New("unsupported attachment type, must be one of: jpeg, png, webp, pdf")
+	errTooManyAttachments        = errors.New("too many attachments")
+	errInvalidAttachmentOwner    = errors.New("invalid attachment owner")
+	errInvalidAttachmentID       = errors.New("invalid attachment id")
+)
+
+// attachmentContentTypes типы файлов, которые можно прикрепить к транзакции
+var attachmentContentTypes = []string{"image/jpeg", "image/png", "image/webp", "application/pdf"}
+
+// AttachmentsStore хранилище файлов, прикрепленных к транзакциям
+type AttachmentsStore interface {
+	Save(userID, fileName string, reader io.Reader) (models.Attachment, error)
+	Open(userID string, attachment models.Attachment) (io.ReadSeekCloser, error)
+	Remove(userID string, attachments []models.Attachment)
+	Exists(userID string, attachment models.Attachment) bool
+}
+
+// AttachmentsService хранит прикрепленные файлы на диске, каждый пользователь в своей директории
+type AttachmentsService struct {
+	path    string
+	maxSize int64
+	logger  *zap.SugaredLogger
+}
+
+// NewAttachmentsService создает хранилище вложений в директории path с ограничением размера файла
+func NewAttachmentsService(path string, maxSize int64, logger *zap.SugaredLogger) *AttachmentsService {
+	return &AttachmentsService{
+		path:    path,
+		maxSize: maxSize,
+		logger:  logger,
+	}
+}
+
+// Save сохраняет файл пользователя. Тип файла определяется по содержимому, а не по
+// заголовкам запроса. Файл сначала пишется во временный, поэтому оборванная загрузка
+// не оставляет недописанных вложений.
+func (as *AttachmentsService) Save(userID, fileName string, reader io.Reader) (models.Attachment, error) {
+	dir, err := as.userDir(userID)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return models.Attachment{}, fmt.Errorf("%w: can't read file: %w", models.ErrBadRequest, err)
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if !slices.Contains(attachmentContentTypes, contentType) {
+		return models.Attachment{}, fmt.Errorf("%w: %w: %s", models.ErrBadRequest, errUnsupportedAttachmentType, contentType)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return models.Attachment{}, fmt.Errorf("failed to create attachments directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, "*.tmp")
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("failed to create attachment file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	size, err := io.Copy(file, io.LimitReader(io.MultiReader(bytes.NewReader(head), reader), as.maxSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("failed to write attachment file: %w", err)
+	}
+	if size > as.maxSize {
+		return models.Attachment{}, fmt.Errorf("%w: %w: limit is %d bytes", models.ErrBadRequest, errAttachmentTooLarge, as.maxSize)
+	}
+
+	attachment := models.Attachment{
+		ID:          uuid.New().String(),
+		FileName:    attachmentFileName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		UploadedAt:  time.Now().UTC(),
+	}
+
+	if err := os.Rename(file.Name(), filepath.Join(dir, attachment.ID)); err != nil {
+		return models.Attachment{}, fmt.Errorf("failed to save attachment file: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// Open открывает файл вложения пользователя на чтение
+func (as *AttachmentsService) Open(userID string, attachment models.Attachment) (io.ReadSeekCloser, error) {
+	dir, err := as.userDir(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAttachmentID(attachment.ID); err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrNotFound, err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, attachment.ID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: attachment file %s not found", models.ErrNotFound, attachment.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open attachment file: %w", err)
+	}
+
+	return file, nil
+}
+
+// Exists проверяет, что файл вложения есть в директории пользователя
+func (as *AttachmentsService) Exists(userID string, attachment models.Attachment) bool {
+	dir, err := as.userDir(userID)
+	if err != nil || validateAttachmentID(attachment.ID) != nil {
+		return false
+	}
+
+	info, err := os.Stat(filepath.Join(dir, attachment.ID))
+
+	return err == nil && info.Mode().IsRegular()
+}
+
+// Remove удаляет файлы вложений, которые больше не относятся ни к одной транзакции.
+// Ошибки только логируются: транзакция к этому моменту уже изменена.
+func (as *AttachmentsService) Remove(userID string, attachments []models.Attachment) {
+	dir, err := as.userDir(userID)
+	if err != nil {
+		as.logger.Errorf("Can't remove attachments of user %s: %v", userID, err)
+		return
+	}
+
+	for _, attachment := range attachments {
+		if err := validateAttachmentID(attachment.ID); err != nil {
+			as.logger.Errorf("Can't remove attachment of user %s: %v", userID, err)
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, attachment.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			as.logger.Errorf("Can't remove attachment %s of user %s: %v", attachment.ID, userID, err)
+		}
+	}
+}
+
+// DeleteUserData удаляет все вложения пользователя
+func (as *AttachmentsService) DeleteUserData(userID string) {
+	dir, err := as.userDir(userID)
+	if err != nil {
+		as.logger.Errorf("Can't remove attachments of user %s: %v", userID, err)
+		return
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		as.logger.Errorf("Can't remove attachments of user %s: %v", userID, err)
+	}
+}
+
+// userDir возвращает директорию вложений пользователя. ID пользователя берется из токена,
+// но на всякий случай проверяется, что путь не выходит за пределы хранилища.
+func (as *AttachmentsService) userDir(userID string) (string, error) {
+	if userID == "" || !filepath.IsLocal(userID) || strings.ContainsAny(userID, `/\`) {
+		return "", fmt.Errorf("%w: %s", errInvalidAttachmentOwner, userID)
+	}
+
+	return filepath.Join(as.path, userID), nil
+}
+
+// validateAttachmentID проверяет, что ID вложения - это UUID, который выдает Save.
+// ID из импортированного архива иначе мог бы указать на файл за пределами хранилища.
+func validateAttachmentID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("%w: %s", errInvalidAttachmentID, id)
+	}
+
+	return nil
+}
+
+// attachmentFileName оставляет от имени файла из запроса только базовое имя
+func attachmentFileName(fileName string) string {
+	fileName = filepath.Base(strings.ReplaceAll(fileName, `\`, "/"))
+	if fileName == "." || fileName == "/" {
+		return "attachment"
+	}
+
+	return fileName
+}
+
+// AddAttachment сохраняет файл и прикрепляет его к транзакции пользователя
+func (ts *TransactionsService) AddAttachment(ctx context.Context, transactionID, fileName string, reader io.Reader) (*models.Attachment, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	shard := ts.userShard(userID)
+
+	// Проверяем транзакцию до записи файла, чтобы не сохранять заведомо лишний файл
+	shard.mux.RLock()
+	_, err := attachableTransaction(shard, transactionID)
+	shard.mux.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, err := ts.attachments.Save(userID, fileName, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	// Пока файл сохранялся, транзакцию могли удалить или прикрепить к ней другие файлы
+	transaction, err := attachableTransaction(shard, transactionID)
+	if err != nil {
+		ts.attachments.Remove(userID, []models.Attachment{attachment})
+		return nil, err
+	}
+
+	transaction.Attachments = append(slices.Clone(transaction.Attachments), attachment)
+	shard.put(transaction)
+
+	return &attachment, nil
+}
+
+// attachableTransaction возвращает транзакцию, к которой можно прикрепить еще один файл.
+// Вызывается под блокировкой шарда.
+func attachableTransaction(shard *userShard, transactionID string) (models.Transaction, error) {
+	transaction, exists := shard.transactions[transactionID]
+	if !exists {
+		return models.Transaction{}, fmt.Errorf("%w: transaction %s not found", models.ErrNotFound, transactionID)
+	}
+	if len(transaction.Attachments) >= models.MaxTransactionAttachments {
+		return models.Transaction{}, fmt.Errorf("%w: %w: limit is %d", models.ErrBadRequest, errTooManyAttachments, models.MaxTransactionAttachments)
+	}
+
+	return transaction, nil
+}
+
+// OpenAttachment возвращает описание и содержимое вложения транзакции пользователя
+func (ts *TransactionsService) OpenAttachment(ctx context.Context, transactionID, attachmentID string) (*models.Attachment, io.ReadSeekCloser, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	shard := ts.userShard(userID)
+
+	shard.mux.RLock()
+	attachment, _, err := findAttachment(shard, transactionID, attachmentID)
+	shard.mux.RUnlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := ts.attachments.Open(userID, attachment)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &attachment, file, nil
+}
+
+// DeleteAttachment открепляет файл от транзакции и удаляет его
+func (ts *TransactionsService) DeleteAttachment(ctx context.Context, transactionID, attachmentID string) error {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	shard := ts.userShard(userID)
+
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	attachment, index, err := findAttachment(shard, transactionID, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	transaction := shard.transactions[transactionID]
+	transaction.Attachments = slices.Delete(slices.Clone(transaction.Attachments), index, index+1)
+	shard.put(transaction)
+
+	ts.attachments.Remove(userID, []models.Attachment{attachment})
+
+	return nil
+}
+
+// findAttachment находит вложение транзакции и его позицию. Вызывается под блокировкой шарда.
+func findAttachment(shard *userShard, transactionID, attachmentID string) (models.Attachment, int, error) {
+	transaction, exists := shard.transactions[transactionID]
+	if !exists {
+		return models.Attachment{}, -1, fmt.Errorf("%w: transaction %s not found", models.ErrNotFound, transactionID)
+	}
+
+	index := slices.IndexFunc(transaction.Attachments, func(attachment models.Attachment) bool {
+		return attachment.ID == attachmentID
+	})
+	if index == -1 {
+		return models.Attachment{}, -1, fmt.Errorf("%w: attachment %s not found", models.ErrNotFound, attachmentID)
+	}
+
+	return transaction.Attachments[index], index, nil
+}
+
+// orphanedAttachments возвращает вложения из before, на которые больше не ссылается
+// ни одна транзакция шарда, включая корзину. Вызывается под блокировкой шарда.
+func orphanedAttachments(shard *userShard, before []models.Attachment) []models.Attachment {
+	if len(before) == 0 {
+		return nil
+	}
+
+	referenced := make(map[string]struct{})
+	for _, transaction := range shard.allTransactions() {
+		for _, attachment := range transaction.Attachments {
+			referenced[attachment.ID] = struct{}{}
+		}
+	}
+
+	var orphaned []models.Attachment
+	for _, attachment := range before {
+		if _, exists := referenced[attachment.ID]; !exists {
+			orphaned = append(orphaned, attachment)
+		}
+	}
+
+	return orphaned
+}
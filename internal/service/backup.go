@@ -16,6 +16,8 @@ import (
 type Backupable interface {
 	GetBackupData() interface{}
 	GetBackupFileName() string
+	// DeleteUserData удаляет все данные пользователя
+	DeleteUserData(userID string)
 }
 
 // BackupService сервис для автоматического бэкапа данных
@@ -47,6 +49,16 @@ func (bs *BackupService) RegisterBackupable(backupable Backupable) {
 	bs.logger.Infof("Registered backupable: %s", backupable.GetBackupFileName())
 }
 
+// DeleteUserData удаляет данные пользователя из всех зарегистрированных объектов
+func (bs *BackupService) DeleteUserData(userID string) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	for _, backupable := range bs.backupables {
+		backupable.DeleteUserData(userID)
+	}
+}
+
 // Start запускает периодический бэкап
 func (bs *BackupService) Start(ctx context.Context) {
 	bs.logger.Info("Starting backup service")
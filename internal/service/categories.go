@@ -2,20 +2,36 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 
 	"spendings-backend/internal/models"
 )
 
+var (
+	errInvalidCategoryColor  = errors.New("color must be in #RRGGBB format")
+	errInvalidCategoryType   = errors.New("category type must be one of: income, expense")
+	errBaseCategoryType      = errors.New("type of base category cannot be changed")
+	errUserCategoryRename    = errors.New("only base categories can be renamed")
+	errCategoryAlreadyExists = errors.New("category already exists")
+)
+
+var categoryColorRegexp = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// CategoriesService хранит категории пользователей. В списке пользователя кроме его
+// категорий лежат настройки базовых категорий: записи с Base, которые скрывают,
+// переименовывают или перекрашивают базовую категорию только для этого пользователя.
 type CategoriesService struct {
 	userCategories map[string][]models.Category // userID -> categories
 	baseCategories []models.Category            // базовые категории для всех пользователей
 	mux            sync.RWMutex
 }
 
-func NewCategoriesService(initialData map[string][]models.Category) *CategoriesService {
+func NewCategoriesService(initialData map[string][]models.Category, baseCategories []models.Category) *CategoriesService {
 	cs := &CategoriesService{}
 
 	if initialData != nil {
@@ -24,60 +40,173 @@ func NewCategoriesService(initialData map[string][]models.Category) *CategoriesS
 		cs.userCategories = make(map[string][]models.Category)
 	}
 
-	// Инициализируем базовые категории
-	cs.baseCategories = []models.Category{
-		{Name: "Еда"},
-		{Name: "Транспорт"},
-		{Name: "Развлечения"},
-		{Name: "Здоровье"},
-		{Name: "Одежда"},
-		{Name: models.IncomeCategory},
-		{Name: "Образование"},
-		{Name: "Подарки"},
-		{Name: "Прочее"},
+	if len(baseCategories) == 0 {
+		baseCategories = models.GetDefaultBaseCategories()
+	}
+
+	// Инициализируем базовые категории, тип по умолчанию определяется по категории доходов
+	cs.baseCategories = make([]models.Category, 0, len(baseCategories))
+	for _, category := range baseCategories {
+		base := copyCategory(category)
+		base.Base, base.Parent, base.SortOrder = "", "", 0
+		if base.Type == "" {
+			base.Type = models.CategoryTypeExpense
+			if base.Name == models.IncomeCategory {
+				base.Type = models.CategoryTypeIncome
+			}
+		}
+		cs.baseCategories = append(cs.baseCategories, base)
 	}
 
 	return cs
 }
 
-func (cs *CategoriesService) GetCategories(ctx context.Context, nameFilter string) ([]models.Category, error) {
+// GetCategories возвращает дерево базовых и пользовательских категорий в порядке SortOrder. С фильтром
+// по названию остаются подходящие категории вместе с родителями и подкатегориями.
+// Архивные категории и их подкатегории возвращаются только с includeArchived.
+func (cs *CategoriesService) GetCategories(ctx context.Context, nameFilter string, includeArchived bool) ([]models.Category, error) {
 	userID := models.ClaimsFromContext(ctx).ID
 
 	cs.mux.RLock()
 	defer cs.mux.RUnlock()
 
-	// Получаем категории пользователя
-	userCategories, exists := cs.userCategories[userID]
-	if !exists {
-		userCategories = []models.Category{}
+	tree := sortCategoryTree(buildCategoryTree(cs.allCategories(userID)))
+	if !includeArchived {
+		tree = pruneArchivedCategories(tree)
 	}
 
-	// Объединяем базовые категории с пользовательскими
-	allCategories := make([]models.Category, 0, len(cs.baseCategories)+len(userCategories))
+	// Применяем фильтр по названию если указан
+	if nameFilter != "" {
+		return filterCategoryTree(tree, strings.ToLower(nameFilter)), nil
+	}
 
-	// Добавляем базовые категории
-	allCategories = append(allCategories, cs.baseCategories...)
+	return tree, nil
+}
 
-	// Добавляем пользовательские категории
-	allCategories = append(allCategories, userCategories...)
+// GetCategoryParents возвращает родителя каждой подкатегории пользователя
+func (cs *CategoriesService) GetCategoryParents(ctx context.Context) (map[string]string, error) {
+	userID := models.ClaimsFromContext(ctx).ID
 
-	// Применяем фильтр по названию если указан
-	if nameFilter != "" {
-		filteredCategories := make([]models.Category, 0)
-		nameFilterLower := strings.ToLower(nameFilter)
+	cs.mux.RLock()
+	defer cs.mux.RUnlock()
 
-		for _, category := range allCategories {
-			if strings.HasPrefix(strings.ToLower(category.Name), nameFilterLower) {
-				filteredCategories = append(filteredCategories, category)
+	return categoryParents(cs.userCategories[userID]), nil
+}
+
+// GetCategoryNames возвращает новые названия базовых категорий, переименованных пользователем
+func (cs *CategoriesService) GetCategoryNames(ctx context.Context) (map[string]string, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	cs.mux.RLock()
+	defer cs.mux.RUnlock()
+
+	names := make(map[string]string)
+	for _, category := range cs.allCategories(userID) {
+		if category.Base != "" && category.Base != category.Name {
+			names[category.Base] = category.Name
+		}
+	}
+
+	return names, nil
+}
+
+// GetIncomeCategories возвращает категории доходов пользователя под названиями,
+// под которыми они хранятся в транзакциях
+func (cs *CategoriesService) GetIncomeCategories(ctx context.Context) (map[string]struct{}, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	cs.mux.RLock()
+	defer cs.mux.RUnlock()
+
+	income := map[string]struct{}{models.IncomeCategory: {}}
+	for _, category := range cs.allCategories(userID) {
+		if category.Type == models.CategoryTypeIncome {
+			income[categoryKey(category)] = struct{}{}
+		}
+	}
+
+	return income, nil
+}
+
+// ResolveCategory возвращает название, под которым категория хранится в транзакциях:
+// для переименованной базовой категории это ее исходное название
+func (cs *CategoriesService) ResolveCategory(ctx context.Context, name string) string {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	cs.mux.RLock()
+	defer cs.mux.RUnlock()
+
+	for _, category := range cs.allCategories(userID) {
+		if category.Base != "" && category.Base != category.Name && strings.EqualFold(category.Name, name) {
+			return category.Base
+		}
+	}
+
+	return name
+}
+
+// allCategories возвращает базовые категории с настройками пользователя и его
+// категории плоским списком. Вызывается под блокировкой.
+func (cs *CategoriesService) allCategories(userID string) []models.Category {
+	return cs.mergeCategories(cs.userCategories[userID])
+}
+
+// mergeCategories применяет настройки базовых категорий из списка пользователя
+// и добавляет его категории. Вызывается под блокировкой.
+func (cs *CategoriesService) mergeCategories(userCategories []models.Category) []models.Category {
+	allCategories := make([]models.Category, 0, len(cs.baseCategories)+len(userCategories))
+
+	for _, base := range cs.baseCategories {
+		category := base
+		category.Base = base.Name
+
+		index := slices.IndexFunc(userCategories, func(override models.Category) bool {
+			return strings.EqualFold(override.Base, base.Name)
+		})
+		if index != -1 {
+			override := userCategories[index]
+			category.Name = override.Name
+			if override.Icon != "" {
+				category.Icon = override.Icon
+			}
+			if override.Color != "" {
+				category.Color = override.Color
 			}
+			category.Archived = override.Archived
+			category.SortOrder = override.SortOrder
 		}
 
-		return filteredCategories, nil
+		allCategories = append(allCategories, category)
+	}
+
+	// Настройки базовых категорий, которых больше нет в конфигурации, не показываются
+	for _, category := range userCategories {
+		if category.Base == "" {
+			allCategories = append(allCategories, category)
+		}
 	}
 
-	return allCategories, nil
+	return allCategories
 }
 
+// findCategory ищет категорию по названию или исходному названию базовой категории
+// без учета регистра. Вызывается под блокировкой.
+func (cs *CategoriesService) findCategory(userID, name string) (models.Category, bool) {
+	return findCategory(cs.allCategories(userID), name)
+}
+
+func findCategory(categories []models.Category, name string) (models.Category, bool) {
+	for _, category := range categories {
+		if strings.EqualFold(category.Name, name) || (category.Base != "" && strings.EqualFold(category.Base, name)) {
+			return category, true
+		}
+	}
+
+	return models.Category{}, false
+}
+
+// CreateCategory создает категорию пользователя. Если указан Parent, категория
+// становится подкатегорией базовой или пользовательской категории.
 func (cs *CategoriesService) CreateCategory(ctx context.Context, category models.Category) error {
 	userID := models.ClaimsFromContext(ctx).ID
 
@@ -94,26 +223,226 @@ func (cs *CategoriesService) CreateCategory(ctx context.Context, category models
 		cs.userCategories[userID] = make([]models.Category, 0)
 	}
 
-	// Проверяем, что категория с таким названием еще не существует
-	for _, existingCategory := range cs.userCategories[userID] {
-		if strings.EqualFold(existingCategory.Name, category.Name) {
-			return fmt.Errorf("%w: category with name '%s' already exists", models.ErrBadRequest, category.Name)
+	// Название не должно совпадать ни с одной категорией, в том числе с исходным
+	// названием переименованной базовой: на него ссылаются старые транзакции
+	if _, exists := cs.findCategory(userID, category.Name); exists {
+		return fmt.Errorf("%w: %w: %s", models.ErrBadRequest, errCategoryAlreadyExists, category.Name)
+	}
+
+	if err := validateCategoryMetadata(category.Color, category.Type); err != nil {
+		return err
+	}
+
+	// Родитель хранится под тем же названием, что и в транзакциях,
+	// тип без явного значения наследуется от родителя
+	defaultType := models.CategoryTypeExpense
+	if category.Parent != "" {
+		parent, exists := cs.findCategory(userID, category.Parent)
+		if !exists {
+			return fmt.Errorf("%w: parent category '%s' not found", models.ErrBadRequest, category.Parent)
+		}
+		category.Parent = categoryKey(parent)
+		if parent.Type != "" {
+			defaultType = parent.Type
 		}
 	}
+	if category.Type == "" {
+		category.Type = defaultType
+	}
 
-	// Проверяем, что категория не конфликтует с базовыми
-	for _, baseCategory := range cs.baseCategories {
-		if strings.EqualFold(baseCategory.Name, category.Name) {
-			return fmt.Errorf("%w: category with name '%s' already exists in base categories", models.ErrBadRequest, category.Name)
+	// Новая категория встает последней среди категорий с тем же родителем
+	sortOrder := 0
+	for _, existingCategory := range cs.userCategories[userID] {
+		if existingCategory.Base == "" && existingCategory.Parent == category.Parent {
+			sortOrder = max(sortOrder, existingCategory.SortOrder)
 		}
 	}
 
 	// Добавляем новую категорию
-	cs.userCategories[userID] = append(cs.userCategories[userID], category)
+	cs.userCategories[userID] = append(cs.userCategories[userID], models.Category{
+		Name:      category.Name,
+		Parent:    category.Parent,
+		Icon:      category.Icon,
+		Color:     category.Color,
+		Type:      category.Type,
+		Archived:  category.Archived,
+		SortOrder: sortOrder + 1,
+	})
+
+	return nil
+}
+
+// UpdateCategory меняет иконку, цвет, тип или архивность категории. Изменения базовой
+// категории, в том числе переименование и скрытие, действуют только для пользователя.
+func (cs *CategoriesService) UpdateCategory(
+	ctx context.Context, name string, request models.UpdateCategoryRequest,
+) (*models.Category, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	var color, categoryType string
+	if request.Color != nil {
+		color = *request.Color
+	}
+	if request.Type != nil {
+		categoryType = *request.Type
+	}
+	if err := validateCategoryMetadata(color, categoryType); err != nil {
+		return nil, err
+	}
+	if request.Type != nil && categoryType == "" {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, errInvalidCategoryType)
+	}
+	if request.Name != nil && strings.TrimSpace(*request.Name) == "" {
+		return nil, fmt.Errorf("%w: category name cannot be empty", models.ErrBadRequest)
+	}
+
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+
+	existing, exists := cs.findCategory(userID, name)
+	if !exists {
+		return nil, fmt.Errorf("%w: category '%s' not found", models.ErrNotFound, name)
+	}
+	key := categoryKey(existing)
+
+	if request.Name != nil {
+		if existing.Base == "" {
+			return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, errUserCategoryRename)
+		}
+		if other, exists := cs.findCategory(userID, *request.Name); exists && categoryKey(other) != key {
+			return nil, fmt.Errorf("%w: %w: %s", models.ErrBadRequest, errCategoryAlreadyExists, *request.Name)
+		}
+	}
+	if request.Type != nil && existing.Base != "" {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, errBaseCategoryType)
+	}
+
+	var category *models.Category
+	if existing.Base != "" {
+		category = cs.baseOverride(userID, existing.Base)
+	} else {
+		index := slices.IndexFunc(cs.userCategories[userID], func(category models.Category) bool {
+			return category.Base == "" && category.Name == existing.Name
+		})
+		category = &cs.userCategories[userID][index]
+	}
+
+	if request.Name != nil {
+		category.Name = *request.Name
+	}
+	if request.Icon != nil {
+		category.Icon = *request.Icon
+	}
+	if request.Color != nil {
+		category.Color = color
+	}
+	if request.Type != nil {
+		category.Type = categoryType
+	}
+	if request.Archived != nil {
+		category.Archived = *request.Archived
+	}
+
+	updated, _ := cs.findCategory(userID, key)
+
+	return &updated, nil
+}
+
+// baseOverride возвращает настройку базовой категории в списке пользователя, создавая ее
+// при первом изменении. Пустые иконка и цвет настройки означают значения базовой категории.
+// Вызывается под блокировкой.
+func (cs *CategoriesService) baseOverride(userID, base string) *models.Category {
+	index := slices.IndexFunc(cs.userCategories[userID], func(category models.Category) bool {
+		return category.Base == base
+	})
+	if index == -1 {
+		cs.userCategories[userID] = append(cs.userCategories[userID], models.Category{Name: base, Base: base})
+		index = len(cs.userCategories[userID]) - 1
+	}
+
+	return &cs.userCategories[userID][index]
+}
+
+// ReorderCategories задает порядок категорий пользователя с общим родителем.
+// В списке должны быть перечислены все такие категории.
+func (cs *CategoriesService) ReorderCategories(ctx context.Context, request models.ReorderCategoriesRequest) error {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+
+	parent := ""
+	if request.Parent != "" {
+		category, exists := cs.findCategory(userID, request.Parent)
+		if !exists {
+			return fmt.Errorf("%w: parent category '%s' not found", models.ErrBadRequest, request.Parent)
+		}
+		parent = categoryKey(category)
+	}
+
+	userCategories := cs.userCategories[userID]
+
+	siblings := 0
+	for _, category := range userCategories {
+		if category.Base == "" && category.Parent == parent {
+			siblings++
+		}
+	}
+
+	positions := make(map[int]int, len(request.Names))
+	for position, name := range request.Names {
+		index := slices.IndexFunc(userCategories, func(category models.Category) bool {
+			return category.Base == "" && strings.EqualFold(category.Name, name)
+		})
+		if index == -1 || userCategories[index].Parent != parent {
+			return fmt.Errorf("%w: category '%s' is not a user category with parent '%s'", models.ErrBadRequest, name, parent)
+		}
+		if _, exists := positions[index]; exists {
+			return fmt.Errorf("%w: category '%s' is listed twice", models.ErrBadRequest, name)
+		}
+		positions[index] = position + 1
+	}
+
+	if len(positions) != siblings {
+		return fmt.Errorf("%w: all %d categories with parent '%s' must be listed", models.ErrBadRequest, siblings, parent)
+	}
+
+	for index, sortOrder := range positions {
+		userCategories[index].SortOrder = sortOrder
+	}
 
 	return nil
 }
 
+// validateCategoryMetadata проверяет цвет и тип категории, пустые значения допустимы
+// validateImportedCategories проверяет категории архива. Родитель, которого нет в архиве,
+// ошибкой не считается: такая категория импортируется корневой.
+func validateImportedCategories(categories []models.Category) error {
+	for _, category := range categories {
+		if strings.TrimSpace(category.Name) == "" {
+			return fmt.Errorf("%w: category name cannot be empty", models.ErrBadRequest)
+		}
+		if err := validateCategoryMetadata(category.Color, category.Type); err != nil {
+			return fmt.Errorf("category %s: %w", category.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func validateCategoryMetadata(color, categoryType string) error {
+	if color != "" && !categoryColorRegexp.MatchString(color) {
+		return fmt.Errorf("%w: %w: %s", models.ErrBadRequest, errInvalidCategoryColor, color)
+	}
+
+	switch categoryType {
+	case "", models.CategoryTypeExpense, models.CategoryTypeIncome:
+		return nil
+	default:
+		return fmt.Errorf("%w: %w: %s", models.ErrBadRequest, errInvalidCategoryType, categoryType)
+	}
+}
+
 // GetBackupData возвращает данные для бэкапа
 func (cs *CategoriesService) GetBackupData() interface{} {
 	cs.mux.RLock()
@@ -122,16 +451,110 @@ func (cs *CategoriesService) GetBackupData() interface{} {
 	// Создаем копию данных для бэкапа
 	backupData := make(map[string][]models.Category)
 	for userID, categories := range cs.userCategories {
-		backupCategories := make([]models.Category, len(categories))
-		for i, category := range categories {
-			backupCategories[i] = models.Category{
-				Name: category.Name,
+		backupData[userID] = backupCategories(categories)
+	}
+
+	return backupData
+}
+
+func backupCategories(categories []models.Category) []models.Category {
+	backupCategories := make([]models.Category, len(categories))
+	for i, category := range categories {
+		backupCategories[i] = copyCategory(category)
+	}
+
+	return backupCategories
+}
+
+// copyCategory копирует категорию без подкатегорий
+func copyCategory(category models.Category) models.Category {
+	return models.Category{
+		Name:      category.Name,
+		Base:      category.Base,
+		Parent:    category.Parent,
+		Icon:      category.Icon,
+		Color:     category.Color,
+		Type:      category.Type,
+		Archived:  category.Archived,
+		SortOrder: category.SortOrder,
+	}
+}
+
+// ExportUserCategories возвращает категории пользователя в формате бэкапа
+func (cs *CategoriesService) ExportUserCategories(ctx context.Context) []models.Category {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	cs.mux.RLock()
+	defer cs.mux.RUnlock()
+
+	return backupCategories(cs.userCategories[userID])
+}
+
+// ImportUserCategories загружает категории пользователя из архива. Категории, совпадающие
+// с базовыми или уже существующими, пропускаются; при replace существующие категории удаляются.
+// Настройки базовых категорий загружаются, если у пользователя еще нет своих.
+func (cs *CategoriesService) ImportUserCategories(ctx context.Context, categories []models.Category, replace bool) (int, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	if err := validateImportedCategories(categories); err != nil {
+		return 0, err
+	}
+
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+
+	userCategories := cs.userCategories[userID]
+	if replace {
+		userCategories = make([]models.Category, 0, len(categories))
+	}
+
+	imported := 0
+	for _, category := range categories {
+		existing, exists := findCategory(cs.mergeCategories(userCategories), category.Name)
+
+		if category.Base != "" {
+			base, isBase := findCategory(cs.baseCategories, category.Base)
+			overridden := slices.ContainsFunc(userCategories, func(override models.Category) bool {
+				return strings.EqualFold(override.Base, category.Base)
+			})
+			if !isBase || overridden || (exists && !strings.EqualFold(categoryKey(existing), base.Name)) {
+				continue
 			}
+
+			added := copyCategory(category)
+			added.Base, added.Parent, added.Type = base.Name, "", ""
+			userCategories = append(userCategories, added)
+			imported++
+			continue
 		}
-		backupData[userID] = backupCategories
+
+		if exists {
+			continue
+		}
+
+		// Родитель должен идти в архиве раньше подкатегории, иначе категория станет корневой
+		parent, exists := findCategory(cs.mergeCategories(userCategories), category.Parent)
+		if !exists {
+			parent = models.Category{}
+		}
+
+		added := copyCategory(category)
+		added.Parent = categoryKey(parent)
+		userCategories = append(userCategories, added)
+		imported++
 	}
 
-	return backupData
+	cs.userCategories[userID] = userCategories
+
+	return imported, nil
+}
+
+// DeleteUserData удаляет категории пользователя
+func (cs *CategoriesService) DeleteUserData(userID string) {
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+
+	delete(cs.userCategories, userID)
 }
 
 // GetBackupFileName возвращает имя файла для бэкапа
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"spendings-backend/internal/models"
+)
+
+// newRenamedCategoriesService создает транзакции пользователя, переименовавшего «Прочее» в «Разное»
+func newRenamedCategoriesService(t *testing.T) (*CategoriesService, *TransactionsService) {
+	t.Helper()
+
+	categories := NewCategoriesService(map[string][]models.Category{
+		"names": {{Name: "Разное", Base: "Прочее"}},
+	}, models.GetDefaultBaseCategories())
+	ts := NewTransactionsService(map[string]map[string]models.Transaction{"names": {
+		"1": {ID: "1", Title: "Ключи", Category: "Прочее", Amount: 300, Date: time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)},
+		"2": {
+			ID: "2", Title: "Гипермаркет", Category: "Еда", Amount: 1500, Date: time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC),
+			Splits: []models.TransactionSplit{{Category: "Еда", Amount: 1000}, {Category: "Прочее", Amount: 500}},
+		},
+	}}, categories, NewRulesService(nil), nil, time.Hour)
+
+	return categories, ts
+}
+
+func TestExportRenamedCategories(t *testing.T) {
+	_, ts := newRenamedCategoriesService(t)
+	es := NewExportService(ts)
+	ctx := userContext("names")
+
+	var csvOutput bytes.Buffer
+	require.NoError(t, es.Export(ctx, &csvOutput, models.ExportOptions{Format: models.ExportFormatCSV}))
+	assert.Contains(t, csvOutput.String(), "02.03.2026;Ключи;Разное;")
+	assert.NotContains(t, csvOutput.String(), "Прочее")
+
+	var jsonOutput bytes.Buffer
+	require.NoError(t, es.Export(ctx, &jsonOutput, models.ExportOptions{Format: models.ExportFormatJSON}))
+
+	var exported []exportTransaction
+	require.NoError(t, json.Unmarshal(jsonOutput.Bytes(), &exported))
+	require.Len(t, exported, 2)
+	assert.Equal(t, "Разное", exported[0].Category)
+	assert.Equal(t, []models.TransactionSplit{{Category: "Еда", Amount: 1000}, {Category: "Разное", Amount: 500}}, exported[1].Splits)
+
+	// Хранимые транзакции не меняются
+	transactions, err := ts.GetAllTransactions(ctx, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	for _, transaction := range transactions {
+		for _, line := range categoryLines(transaction) {
+			assert.NotEqual(t, "Разное", line.Category)
+		}
+	}
+}
+
+func TestSuggestRenamedCategories(t *testing.T) {
+	_, ts := newRenamedCategoriesService(t)
+
+	suggestions, err := ts.SuggestCategories(userContext("names"), "Ключи", nil, 1)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "Разное", suggestions[0].Category)
+}
+
+func TestMonthlyReportRenamedCategories(t *testing.T) {
+	categories, ts := newRenamedCategoriesService(t)
+	rs, err := NewReportService(NewStatisticsService(ts, categories, NewPayeesService(nil)), ts, categories)
+	require.NoError(t, err)
+
+	data, err := rs.GetMonthlyReport(userContext("names"), time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	pages := parsePDF(t, data).pages(t)
+	require.Len(t, pages, 1)
+
+	// Категория называется одинаково в разбивке по категориям и в крупнейших расходах
+	renamed := 0
+	for _, text := range pageTexts(pages[0]) {
+		assert.False(t, strings.Contains(text, "Прочее"), text)
+		if text == "Разное" {
+			renamed++
+		}
+	}
+	assert.Equal(t, 2, renamed)
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"spendings-backend/internal/models"
+)
+
+// categoryKey возвращает название, под которым на категорию ссылаются транзакции и подкатегории
+func categoryKey(category models.Category) string {
+	if category.Base != "" {
+		return category.Base
+	}
+
+	return category.Name
+}
+
+// buildCategoryTree собирает плоский список категорий в дерево. Категории, родитель
+// которых не найден, становятся корневыми. Порядок категорий сохраняется.
+func buildCategoryTree(categories []models.Category) []models.Category {
+	children := make(map[string][]models.Category)
+	names := make(map[string]struct{}, len(categories))
+	for _, category := range categories {
+		names[categoryKey(category)] = struct{}{}
+	}
+
+	var roots []models.Category
+	for _, category := range categories {
+		if _, exists := names[category.Parent]; exists && category.Parent != categoryKey(category) {
+			children[category.Parent] = append(children[category.Parent], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	// visited защищает от циклов в поврежденных данных
+	visited := make(map[string]struct{}, len(categories))
+	var attach func(category models.Category) models.Category
+	attach = func(category models.Category) models.Category {
+		visited[categoryKey(category)] = struct{}{}
+		category.Children = nil
+		for _, child := range children[categoryKey(category)] {
+			if _, seen := visited[categoryKey(child)]; !seen {
+				category.Children = append(category.Children, attach(child))
+			}
+		}
+		return category
+	}
+
+	tree := make([]models.Category, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, attach(root))
+	}
+
+	return tree
+}
+
+// filterCategoryTree оставляет категории, название которых начинается с prefix,
+// вместе с их родителями и подкатегориями
+func filterCategoryTree(tree []models.Category, prefix string) []models.Category {
+	filtered := make([]models.Category, 0)
+	for _, category := range tree {
+		if strings.HasPrefix(strings.ToLower(category.Name), prefix) {
+			filtered = append(filtered, category)
+			continue
+		}
+
+		if children := filterCategoryTree(category.Children, prefix); len(children) > 0 {
+			category.Children = children
+			filtered = append(filtered, category)
+		}
+	}
+
+	return filtered
+}
+
+// categoryParents возвращает родителя каждой подкатегории
+func categoryParents(categories []models.Category) map[string]string {
+	parents := make(map[string]string)
+	for _, category := range categories {
+		if category.Parent != "" {
+			parents[category.Name] = category.Parent
+		}
+	}
+
+	return parents
+}
+
+// sortCategoryTree упорядочивает категории каждого уровня по SortOrder, при равных
+// значениях сохраняется исходный порядок: базовые категории идут первыми
+func sortCategoryTree(tree []models.Category) []models.Category {
+	slices.SortStableFunc(tree, func(a, b models.Category) int {
+		return cmp.Compare(a.SortOrder, b.SortOrder)
+	})
+	for i := range tree {
+		tree[i].Children = sortCategoryTree(tree[i].Children)
+	}
+
+	return tree
+}
+
+// pruneArchivedCategories убирает архивные категории вместе с их подкатегориями
+func pruneArchivedCategories(tree []models.Category) []models.Category {
+	pruned := make([]models.Category, 0, len(tree))
+	for _, category := range tree {
+		if category.Archived {
+			continue
+		}
+		category.Children = pruneArchivedCategories(category.Children)
+		pruned = append(pruned, category)
+	}
+
+	return pruned
+}
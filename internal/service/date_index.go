@@ -0,0 +1,106 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"spendings-backend/internal/models"
+)
+
+// dateIndexEntry элемент индекса транзакций, упорядоченного по дате
+type dateIndexEntry struct {
+	date time.Time
+	id   string
+}
+
+// dateIndex хранит ID транзакций пользователя, отсортированные по (дата, ID)
+// по возрастанию. Позволяет выбирать диапазон дат и страницу за O(log n + k)
+// без копирования и сортировки всех транзакций пользователя.
+// Не потокобезопасен, доступ защищается мьютексом шарда пользователя.
+type dateIndex struct {
+	entries []dateIndexEntry
+}
+
+func newDateIndex() *dateIndex {
+	return &dateIndex{}
+}
+
+func compareDateIndexEntries(a, b dateIndexEntry) int {
+	if result := a.date.Compare(b.date); result != 0 {
+		return result
+	}
+
+	return strings.Compare(a.id, b.id)
+}
+
+// add добавляет транзакцию в индекс
+func (di *dateIndex) add(transaction models.Transaction) {
+	entry := dateIndexEntry{date: transaction.Date, id: transaction.ID}
+
+	position, found := slices.BinarySearchFunc(di.entries, entry, compareDateIndexEntries)
+	if found {
+		return
+	}
+
+	di.entries = slices.Insert(di.entries, position, entry)
+}
+
+// remove удаляет транзакцию из индекса
+func (di *dateIndex) remove(transaction models.Transaction) {
+	entry := dateIndexEntry{date: transaction.Date, id: transaction.ID}
+
+	position, found := slices.BinarySearchFunc(di.entries, entry, compareDateIndexEntries)
+	if !found {
+		return
+	}
+
+	di.entries = slices.Delete(di.entries, position, position+1)
+}
+
+// rangeOf возвращает записи с датами в [fromDate, toDate].
+// Нулевые границы не ограничивают диапазон. Возвращаемый срез нельзя изменять.
+func (di *dateIndex) rangeOf(fromDate, toDate time.Time) []dateIndexEntry {
+	lo := 0
+	if !fromDate.IsZero() {
+		lo, _ = slices.BinarySearchFunc(di.entries, fromDate, func(entry dateIndexEntry, target time.Time) int {
+			return entry.date.Compare(target)
+		})
+	}
+
+	hi := len(di.entries)
+	if !toDate.IsZero() {
+		// Ищем первую запись строго позже toDate
+		hi, _ = slices.BinarySearchFunc(di.entries, toDate, func(entry dateIndexEntry, target time.Time) int {
+			if entry.date.After(target) {
+				return 1
+			}
+			return -1
+		})
+	}
+
+	if lo > hi {
+		return nil
+	}
+
+	return di.entries[lo:hi]
+}
+
+// positionAfter возвращает позицию первой записи среза, которая идет после
+// ключа курсора в порядке сортировки. Для обратного порядка позиция считается
+// от конца среза.
+func positionAfter(entries []dateIndexEntry, key models.Transaction, desc bool) int {
+	entry := dateIndexEntry{date: key.Date, id: key.ID}
+
+	position, found := slices.BinarySearchFunc(entries, entry, compareDateIndexEntries)
+	if desc {
+		// Записи перед position идут после курсора при обходе с конца
+		return len(entries) - position
+	}
+
+	if found {
+		position++
+	}
+
+	return position
+}
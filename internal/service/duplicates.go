@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"spendings-backend/internal/models"
+)
+
+// duplicateIndex группирует ID транзакций пользователя по отпечатку
+type duplicateIndex struct {
+	groups map[string][]string // fingerprint -> transactionIDs
+}
+
+func newDuplicateIndex() *duplicateIndex {
+	return &duplicateIndex{
+		groups: make(map[string][]string),
+	}
+}
+
+func (di *duplicateIndex) add(transaction models.Transaction) {
+	fingerprint := transactionFingerprint(transaction.Date, transaction.Amount, transaction.Title)
+
+	di.groups[fingerprint] = append(di.groups[fingerprint], transaction.ID)
+}
+
+func (di *duplicateIndex) remove(transaction models.Transaction) {
+	fingerprint := transactionFingerprint(transaction.Date, transaction.Amount, transaction.Title)
+
+	ids := slices.DeleteFunc(di.groups[fingerprint], func(id string) bool {
+		return id == transaction.ID
+	})
+	if len(ids) == 0 {
+		delete(di.groups, fingerprint)
+		return
+	}
+
+	di.groups[fingerprint] = ids
+}
+
+// transactionFingerprint строит отпечаток транзакции из даты, суммы в копейках
+// и названия без регистра, знаков препинания и лишних пробелов
+func transactionFingerprint(date time.Time, amount float64, title string) string {
+	return fmt.Sprintf("%s|%d|%s",
+		date.Format("2006-01-02"),
+		int64(math.Round(amount*100)),
+		strings.Join(tokenize(title), " "),
+	)
+}
+
+// FindDuplicates возвращает ID сохраненных транзакций с тем же отпечатком, что и у запроса
+func (ts *TransactionsService) FindDuplicates(ctx context.Context, req models.CreateTransactionRequest) ([]string, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid date format: %w", models.ErrBadRequest, err)
+	}
+
+	shard := ts.userShard(userID)
+
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	return slices.Clone(shard.duplicateIndex.groups[transactionFingerprint(date, req.Amount, req.Title)]), nil
+}
+
+// GetDuplicates возвращает группы транзакций пользователя с одинаковым отпечатком,
+// чтобы пользователь сам решил, какие из них удалить. Новые группы идут первыми.
+func (ts *TransactionsService) GetDuplicates(ctx context.Context) (*models.DuplicatesResponse, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	shard := ts.userShard(userID)
+
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	groups := make([]models.DuplicateGroup, 0)
+	for fingerprint, ids := range shard.duplicateIndex.groups {
+		if len(ids) < 2 {
+			continue
+		}
+
+		transactions := make([]models.Transaction, 0, len(ids))
+		for _, id := range ids {
+			transactions = append(transactions, shard.transactions[id])
+		}
+		slices.SortFunc(transactions, func(a, b models.Transaction) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+
+		groups = append(groups, models.DuplicateGroup{
+			Fingerprint:  fingerprint,
+			Transactions: transactions,
+		})
+	}
+
+	slices.SortFunc(groups, func(a, b models.DuplicateGroup) int {
+		if c := b.Transactions[0].Date.Compare(a.Transactions[0].Date); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Fingerprint, b.Fingerprint)
+	})
+
+	return &models.DuplicatesResponse{
+		Groups: groups,
+	}, nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errUnknownExportFormat = errors.New("unknown export format")
+	errUnknownLocale       = errors.New("unknown locale")
+)
+
+type TransactionsStreamer interface {
+	StreamTransactions(ctx context.Context, filter models.TransactionsFilter, yield func(batch []models.Transaction) error) error
+}
+
+// exportLocale заголовки столбцов и форматы значений для языка выгрузки
+type exportLocale struct {
+	headers            []string
+	sheetName          string
+	csvDelimiter       rune
+	dateLayout         string
+	xlsxDateFormat     string
+	decimalSeparator   string
+	thousandsSeparator string
+}
+
+var exportLocales = map[string]exportLocale{
+	models.LocaleRU: {
+		headers:            []string{"Дата", "Название", "Категория", "Сумма"},
+		sheetName:          "Транзакции",
+		csvDelimiter:       ';',
+		dateLayout:         "02.01.2006",
+		xlsxDateFormat:     "dd.mm.yyyy",
+		decimalSeparator:   ",",
+		thousandsSeparator: " ",
+	},
+	models.LocaleEN: {
+		headers:            []string{"Date", "Title", "Category", "Amount"},
+		sheetName:          "Transactions",
+		csvDelimiter:       ',',
+		dateLayout:         "2006-01-02",
+		xlsxDateFormat:     "yyyy-mm-dd",
+		decimalSeparator:   ".",
+		thousandsSeparator: ",",
+	},
+}
+
+// exportTransaction транзакция в выгрузке JSON
+type exportTransaction struct {
+	ID       string                    `json:"id"`
+	Date     string                    `json:"date"`
+	Title    string                    `json:"title"`
+	Category string                    `json:"category"`
+	Amount   float64                   `json:"amount"`
+	Tags     []string                  `json:"tags,omitempty"`
+	Splits   []models.TransactionSplit `json:"splits,omitempty"`
+	Note     string                    `json:"note,omitempty"`
+}
+
+// ExportService сервис выгрузки транзакций в файлы
+type ExportService struct {
+	transactionsService TransactionsStreamer
+}
+
+// NewExportService создает новый сервис выгрузки
+func NewExportService(transactionsService TransactionsStreamer) *ExportService {
+	return &ExportService{
+		transactionsService: transactionsService,
+	}
+}
+
+// Export записывает отфильтрованные транзакции пользователя в writer в формате csv, xlsx или json.
+// Транзакции читаются и записываются пачками, выгрузка целиком в памяти не хранится.
+// Ошибка параметров возвращается до записи первого байта.
+func (es *ExportService) Export(ctx context.Context, writer io.Writer, options models.ExportOptions) error {
+	if options.Locale == "" {
+		options.Locale = models.LocaleRU
+	}
+
+	locale, exists := exportLocales[options.Locale]
+	if !exists {
+		return fmt.Errorf("%w: %w: %s, must be one of: ru, en", models.ErrBadRequest, errUnknownLocale, options.Locale)
+	}
+
+	switch options.Format {
+	case models.ExportFormatCSV:
+		return es.exportCSV(ctx, writer, options.Filter, locale)
+	case models.ExportFormatXLSX:
+		return es.exportXLSX(ctx, writer, options.Filter, locale)
+	case models.ExportFormatJSON:
+		return es.exportJSON(ctx, writer, options.Filter)
+	default:
+		return fmt.Errorf("%w: %w: %s, must be one of: csv, xlsx, json", models.ErrBadRequest, errUnknownExportFormat, options.Format)
+	}
+}
+
+func (es *ExportService) exportCSV(ctx context.Context, writer io.Writer, filter models.TransactionsFilter, locale exportLocale) error {
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Comma = locale.csvDelimiter
+
+	if err := csvWriter.Write(locale.headers); err != nil {
+		return fmt.Errorf("can't write csv header: %w", err)
+	}
+
+	err := es.transactionsService.StreamTransactions(ctx, filter, func(batch []models.Transaction) error {
+		for _, transaction := range batch {
+			record := []string{
+				transaction.Date.Format(locale.dateLayout),
+				transaction.Title,
+				transaction.Category,
+				formatAmount(transaction.Amount, locale),
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return fmt.Errorf("can't write csv record: %w", err)
+			}
+		}
+
+		csvWriter.Flush()
+		return csvWriter.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func (es *ExportService) exportJSON(ctx context.Context, writer io.Writer, filter models.TransactionsFilter) error {
+	if _, err := io.WriteString(writer, "["); err != nil {
+		return err
+	}
+
+	first := true
+	err := es.transactionsService.StreamTransactions(ctx, filter, func(batch []models.Transaction) error {
+		for _, transaction := range batch {
+			buf, err := json.Marshal(exportTransaction{
+				ID:       transaction.ID,
+				Date:     transaction.Date.Format("2006-01-02"),
+				Title:    transaction.Title,
+				Category: transaction.Category,
+				Amount:   transaction.Amount,
+				Tags:     transaction.Tags,
+				Splits:   transaction.Splits,
+				Note:     transaction.Note,
+			})
+			if err != nil {
+				return fmt.Errorf("can't marshal transaction: %w", err)
+			}
+
+			if !first {
+				buf = append([]byte(","), buf...)
+			}
+			first = false
+
+			if _, err := writer.Write(buf); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(writer, "]")
+	return err
+}
+
+// formatAmount форматирует сумму с двумя знаками после запятой и разделителями разрядов языка выгрузки
+func formatAmount(amount float64, locale exportLocale) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	cents := int64(math.Round(amount * 100))
+	integerPart := strconv.FormatInt(cents/100, 10)
+
+	var grouped strings.Builder
+	for i, digit := range integerPart {
+		if i > 0 && (len(integerPart)-i)%3 == 0 {
+			grouped.WriteString(locale.thousandsSeparator)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return fmt.Sprintf("%s%s%s%02d", sign, grouped.String(), locale.decimalSeparator, cents%100)
+}
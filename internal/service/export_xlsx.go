@@ -0,0 +1,157 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"spendings-backend/internal/models"
+)
+
+// Статичные части книги XLSX. Лист с транзакциями пишется потоком в xlsxSheetPath.
+const (
+	xlsxSheetPath = "xl/worksheets/sheet1.xml"
+
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+
+	// Стили: 1 - заголовок, 2 - дата в формате языка выгрузки, 3 - сумма с разделителями разрядов
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="%s"/></numFmts>` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="4">` +
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`</cellXfs>` +
+		`</styleSheet>`
+
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<cols><col min="1" max="1" width="12" customWidth="1"/><col min="2" max="2" width="40" customWidth="1"/>` +
+		`<col min="3" max="3" width="20" customWidth="1"/><col min="4" max="4" width="14" customWidth="1"/></cols>` +
+		`<sheetData>`
+
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// excelEpoch начало отсчета дат Excel
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// exportXLSX записывает книгу Excel с одним листом. Файл собирается в zip-архив
+// на лету, поэтому строки листа не накапливаются в памяти.
+func (es *ExportService) exportXLSX(ctx context.Context, writer io.Writer, filter models.TransactionsFilter, locale exportLocale) error {
+	archive := zip.NewWriter(writer)
+
+	staticParts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, locale.sheetName)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", fmt.Sprintf(xlsxStyles, locale.xlsxDateFormat)},
+	}
+
+	for _, part := range staticParts {
+		partWriter, err := archive.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("can't create xlsx part %s: %w", part.name, err)
+		}
+		if _, err := io.WriteString(partWriter, part.content); err != nil {
+			return fmt.Errorf("can't write xlsx part %s: %w", part.name, err)
+		}
+	}
+
+	partWriter, err := archive.Create(xlsxSheetPath)
+	if err != nil {
+		return fmt.Errorf("can't create xlsx sheet: %w", err)
+	}
+	sheet := bufio.NewWriter(partWriter)
+
+	sheet.WriteString(xlsxSheetStart)
+
+	sheet.WriteString(`<row r="1">`)
+	for column, header := range locale.headers {
+		writeXLSXStringCell(sheet, column, 1, header, 1)
+	}
+	sheet.WriteString(`</row>`)
+
+	rowNumber := 1
+	err = es.transactionsService.StreamTransactions(ctx, filter, func(batch []models.Transaction) error {
+		for _, transaction := range batch {
+			rowNumber++
+
+			fmt.Fprintf(sheet, `<row r="%d">`, rowNumber)
+			fmt.Fprintf(sheet, `<c r="A%d" s="2"><v>%d</v></c>`, rowNumber, excelDate(transaction.Date))
+			writeXLSXStringCell(sheet, 1, rowNumber, transaction.Title, 0)
+			writeXLSXStringCell(sheet, 2, rowNumber, transaction.Category, 0)
+			fmt.Fprintf(sheet, `<c r="D%d" s="3"><v>%s</v></c>`, rowNumber, strconv.FormatFloat(transaction.Amount, 'f', -1, 64))
+			sheet.WriteString(`</row>`)
+		}
+
+		return sheet.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	sheet.WriteString(xlsxSheetEnd)
+	if err := sheet.Flush(); err != nil {
+		return fmt.Errorf("can't write xlsx sheet: %w", err)
+	}
+
+	return archive.Close()
+}
+
+// excelDate возвращает номер дня в системе дат Excel
+func excelDate(date time.Time) int {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	return int(day.Sub(excelEpoch).Hours() / 24)
+}
+
+// writeXLSXStringCell записывает строковую ячейку без общей таблицы строк
+func writeXLSXStringCell(sheet *bufio.Writer, column, row int, value string, style int) {
+	fmt.Fprintf(sheet, `<c r="%c%d" t="inlineStr"`, 'A'+column, row)
+	if style != 0 {
+		fmt.Fprintf(sheet, ` s="%d"`, style)
+	}
+	sheet.WriteString(`><is><t xml:space="preserve">`)
+	xml.EscapeText(sheet, []byte(strings.ToValidUTF8(value, "")))
+	sheet.WriteString(`</t></is></c>`)
+}
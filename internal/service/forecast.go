@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"spendings-backend/internal/models"
+)
+
+// forecastHistoryMonths количество прошедших месяцев, по которым оценивается тренд трат
+const forecastHistoryMonths = 6
+
+// recurringRuleKey идентифицирует повторяющееся правило среди исторических транзакций
+type recurringRuleKey struct {
+	title    string
+	category string
+	amount   float64
+}
+
+// categoryTrend линейный тренд помесячных сумм по категории
+type categoryTrend struct {
+	intercept float64
+	slope     float64
+	stdDev    float64
+	// historyLen количество месяцев истории, по которым построен тренд
+	historyLen int
+}
+
+// projected возвращает ожидаемую сумму за k-й месяц после текущего
+func (ct categoryTrend) projected(k int) float64 {
+	value := ct.intercept + ct.slope*float64(ct.historyLen-1+k)
+	if value < 0 {
+		return 0
+	}
+
+	return value
+}
+
+// repeatSchedule разобранная строка повторения транзакции
+type repeatSchedule struct {
+	days     map[int]struct{}
+	weekdays map[time.Weekday]struct{}
+}
+
+func parseRepeatSchedule(repeatTime string) repeatSchedule {
+	schedule := repeatSchedule{
+		days:     make(map[int]struct{}),
+		weekdays: make(map[time.Weekday]struct{}),
+	}
+
+	for _, part := range strings.Split(repeatTime, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if day, err := strconv.Atoi(part); err == nil {
+			schedule.days[day] = struct{}{}
+			continue
+		}
+
+		if weekday, err := convertToWeekDay(part); err == nil {
+			schedule.weekdays[weekday] = struct{}{}
+		}
+	}
+
+	return schedule
+}
+
+// matches проверяет, приходится ли повторение на указанный день.
+// Числа, которых нет в месяце (например, 31 в ноябре), приходятся на последний день месяца.
+func (rs repeatSchedule) matches(day time.Time) bool {
+	if _, ok := rs.weekdays[day.Weekday()]; ok {
+		return true
+	}
+
+	if _, ok := rs.days[day.Day()]; ok {
+		return true
+	}
+
+	lastDay := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	if day.Day() != lastDay {
+		return false
+	}
+
+	for scheduledDay := range rs.days {
+		if scheduledDay > lastDay {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetForecast строит прогноз баланса на months месяцев вперед.
+// Прогноз складывается из запланированных (повторяющихся и будущих) транзакций
+// и линейного тренда нерегулярных трат по категориям за последние месяцы.
+func (ss *StatisticsService) GetForecast(ctx context.Context, months int) (*models.ForecastResponse, error) {
+	if months < 1 || months > models.MaxForecastMonths {
+		return nil, fmt.Errorf("%w: months must be between 1 and %d", models.ErrBadRequest, models.MaxForecastMonths)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	transactions, err := ss.transactionsService.GetAllTransactions(ctx, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	// Делим транзакции на прошедшие и запланированные на будущее
+	var past, future []models.Transaction
+	for _, transaction := range transactions {
+		if transaction.Date.After(today) {
+			future = append(future, transaction)
+		} else {
+			past = append(past, transaction)
+		}
+	}
+
+	income, err := ss.incomeCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	startBalance := ss.calculateGeneralStatistics(past, income).Balance
+
+	// Повторяющиеся правила - транзакции с активной строкой повторения
+	rules := make(map[recurringRuleKey]struct{})
+	for _, transaction := range transactions {
+		if transaction.RepeatTime != "" {
+			rules[ruleKeyOf(transaction)] = struct{}{}
+		}
+	}
+
+	trends := ss.estimateCategoryTrends(past, rules, today)
+
+	points := make([]models.ForecastPoint, 0, months)
+	expected := startBalance
+	variance := 0.0
+
+	for k := 1; k <= months; k++ {
+		windowStart := today.AddDate(0, k-1, 0)
+		windowEnd := today.AddDate(0, k, 0)
+
+		point := models.ForecastPoint{
+			Date: windowEnd.Format("2006-01-02"),
+		}
+
+		// Разовые транзакции, запланированные на будущее
+		for _, transaction := range future {
+			if transaction.Date.After(windowStart) && !transaction.Date.After(windowEnd) {
+				addScheduled(&point, income, transaction.Category, transaction.Amount)
+			}
+		}
+
+		// Будущие повторения
+		for _, transaction := range transactions {
+			if transaction.RepeatTime == "" {
+				continue
+			}
+
+			schedule := parseRepeatSchedule(transaction.RepeatTime)
+			for day := windowStart.AddDate(0, 0, 1); !day.After(windowEnd); day = day.AddDate(0, 0, 1) {
+				if day.Before(transaction.NextAppearDate) || !schedule.matches(day) {
+					continue
+				}
+				addScheduled(&point, income, transaction.Category, transaction.Amount)
+			}
+		}
+
+		// Нерегулярные траты и доходы по тренду
+		for category, trend := range trends {
+			if income.isIncome(category) {
+				point.DiscretionaryIncome += trend.projected(k)
+			} else {
+				point.DiscretionaryExpenses += trend.projected(k)
+			}
+			variance += trend.stdDev * trend.stdDev
+		}
+
+		expected += point.ScheduledIncome - point.ScheduledExpenses +
+			point.DiscretionaryIncome - point.DiscretionaryExpenses
+		deviation := math.Sqrt(variance)
+
+		point.Expected = expected
+		point.Optimistic = expected + deviation
+		point.Pessimistic = expected - deviation
+
+		points = append(points, point)
+	}
+
+	return &models.ForecastResponse{
+		StartBalance: startBalance,
+		FromDate:     today.Format("2006-01-02"),
+		Months:       months,
+		Points:       points,
+	}, nil
+}
+
+// estimateCategoryTrends строит линейный тренд помесячных нерегулярных сумм по каждой категории.
+// Транзакции, совпадающие с активными повторяющимися правилами, не учитываются,
+// так как они уже входят в прогноз как запланированные.
+func (ss *StatisticsService) estimateCategoryTrends(past []models.Transaction, rules map[recurringRuleKey]struct{}, today time.Time) map[string]categoryTrend {
+	historyStart := today.AddDate(0, -forecastHistoryMonths, 0)
+
+	// Не учитываем месяцы до первой транзакции пользователя, чтобы не занижать тренд
+	firstDate := today
+	for _, transaction := range past {
+		if transaction.Date.Before(firstDate) {
+			firstDate = transaction.Date
+		}
+	}
+
+	historyLen := forecastHistoryMonths
+	for historyLen > 1 && !today.AddDate(0, -historyLen+1, 0).After(firstDate) {
+		historyLen--
+	}
+
+	totals := make(map[string][]float64)
+	for _, transaction := range past {
+		if !transaction.Date.After(historyStart) {
+			continue
+		}
+		if _, isRecurring := rules[ruleKeyOf(transaction)]; isRecurring {
+			continue
+		}
+
+		// Индекс месяца: historyLen-1 - последний месяц перед сегодняшним днем
+		monthsAgo := 0
+		for !transaction.Date.After(today.AddDate(0, -monthsAgo-1, 0)) {
+			monthsAgo++
+		}
+		if monthsAgo >= historyLen {
+			continue
+		}
+
+		for _, line := range categoryLines(transaction) {
+			if totals[line.Category] == nil {
+				totals[line.Category] = make([]float64, historyLen)
+			}
+			totals[line.Category][historyLen-1-monthsAgo] += line.Amount
+		}
+	}
+
+	trends := make(map[string]categoryTrend, len(totals))
+	for category, values := range totals {
+		trends[category] = fitTrend(values)
+	}
+
+	return trends
+}
+
+// fitTrend вычисляет линейную регрессию методом наименьших квадратов
+func fitTrend(values []float64) categoryTrend {
+	n := float64(len(values))
+
+	var mean float64
+	for _, value := range values {
+		mean += value
+	}
+	mean /= n
+
+	trend := categoryTrend{
+		intercept:  mean,
+		historyLen: len(values),
+	}
+
+	if len(values) < 3 {
+		// Для короткой истории используем среднее без наклона
+		var squares float64
+		for _, value := range values {
+			squares += (value - mean) * (value - mean)
+		}
+		if len(values) > 1 {
+			trend.stdDev = math.Sqrt(squares / (n - 1))
+		}
+
+		return trend
+	}
+
+	xMean := (n - 1) / 2
+
+	var covariance, xVariance float64
+	for i, value := range values {
+		covariance += (float64(i) - xMean) * (value - mean)
+		xVariance += (float64(i) - xMean) * (float64(i) - xMean)
+	}
+
+	trend.slope = covariance / xVariance
+	trend.intercept = mean - trend.slope*xMean
+
+	var residuals float64
+	for i, value := range values {
+		residual := value - (trend.intercept + trend.slope*float64(i))
+		residuals += residual * residual
+	}
+	trend.stdDev = math.Sqrt(residuals / (n - 2))
+
+	return trend
+}
+
+func ruleKeyOf(transaction models.Transaction) recurringRuleKey {
+	return recurringRuleKey{
+		title:    transaction.Title,
+		category: transaction.Category,
+		amount:   transaction.Amount,
+	}
+}
+
+func addScheduled(point *models.ForecastPoint, income incomeCategories, category string, amount float64) {
+	if income.isIncome(category) {
+		point.ScheduledIncome += amount
+	} else {
+		point.ScheduledExpenses += amount
+	}
+}
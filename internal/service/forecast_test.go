@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"spendings-backend/internal/models"
+	"spendings-backend/internal/service/mocks"
+)
+
+// forecastToday начало текущего дня, так же как его считает GetForecast
+func forecastToday() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// monthAgo возвращает дату внутри месяца истории, который был monthsAgo месяцев назад
+func monthAgo(today time.Time, monthsAgo int) time.Time {
+	return today.AddDate(0, -monthsAgo, 0).AddDate(0, 0, -1)
+}
+
+func newForecastService(t *testing.T, transactions []models.Transaction, income map[string]struct{}) *StatisticsService {
+	ctrl := gomock.NewController(t)
+
+	transactionsProvider := mocks.NewMockTransactionsProvider(ctrl)
+	transactionsProvider.EXPECT().
+		GetAllTransactions(gomock.Any(), time.Time{}, time.Time{}).
+		Return(transactions, nil)
+
+	categoriesProvider := mocks.NewMockCategoriesProvider(ctrl)
+	categoriesProvider.EXPECT().
+		GetIncomeCategories(gomock.Any()).
+		Return(income, nil)
+
+	return NewStatisticsService(transactionsProvider, categoriesProvider, mocks.NewMockPayeesProvider(ctrl))
+}
+
+func TestGetForecastScheduled(t *testing.T) {
+	today := forecastToday()
+	// История старше горизонта тренда, поэтому в прогнозе есть только запланированные суммы
+	longAgo := today.AddDate(-1, 0, 0)
+
+	transactions := []models.Transaction{
+		{ID: "1", Title: "Зарплата", Category: models.IncomeCategory, Amount: 1000, Date: longAgo},
+		{ID: "2", Title: "Продукты", Category: "Еда", Amount: 300, Date: longAgo},
+		{ID: "3", Title: "Аренда", Category: "Жилье", Amount: 100, Date: longAgo, RepeatTime: "15", NextAppearDate: today},
+		{ID: "4", Title: "Ремонт", Category: "Жилье", Amount: 200, Date: today.AddDate(0, 0, 10)},
+		{ID: "5", Title: "Заказ", Category: "Фриланс", Amount: 500, Date: today.AddDate(0, 0, 40)},
+	}
+	income := map[string]struct{}{models.IncomeCategory: {}, "Фриланс": {}}
+
+	forecast, err := newForecastService(t, transactions, income).GetForecast(context.Background(), 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, 600.0, forecast.StartBalance)
+	assert.Equal(t, today.Format("2006-01-02"), forecast.FromDate)
+	require.Len(t, forecast.Points, 2)
+
+	first := forecast.Points[0]
+	assert.Equal(t, today.AddDate(0, 1, 0).Format("2006-01-02"), first.Date)
+	assert.Equal(t, 0.0, first.ScheduledIncome)
+	assert.Equal(t, 300.0, first.ScheduledExpenses)
+	assert.Equal(t, 300.0, first.Expected)
+
+	second := forecast.Points[1]
+	assert.Equal(t, 500.0, second.ScheduledIncome)
+	assert.Equal(t, 100.0, second.ScheduledExpenses)
+	assert.Equal(t, 700.0, second.Expected)
+
+	for _, point := range forecast.Points {
+		assert.Zero(t, point.DiscretionaryIncome)
+		assert.Zero(t, point.DiscretionaryExpenses)
+		assert.Equal(t, point.Expected, point.Optimistic)
+		assert.Equal(t, point.Expected, point.Pessimistic)
+	}
+}
+
+func TestGetForecastLinearTrend(t *testing.T) {
+	today := forecastToday()
+
+	// Траты растут на 10 в месяц: 100, 110, ..., 150 за последние шесть месяцев
+	var transactions []models.Transaction
+	for monthsAgo := 0; monthsAgo < forecastHistoryMonths; monthsAgo++ {
+		transactions = append(transactions, models.Transaction{
+			ID:       string(rune('a' + monthsAgo)),
+			Title:    "Кафе",
+			Category: "Еда",
+			Amount:   150 - 10*float64(monthsAgo),
+			Date:     monthAgo(today, monthsAgo),
+		})
+	}
+
+	forecast, err := newForecastService(t, transactions, nil).GetForecast(context.Background(), 2)
+	require.NoError(t, err)
+
+	assert.InDelta(t, -750.0, forecast.StartBalance, 1e-9)
+	require.Len(t, forecast.Points, 2)
+
+	assert.InDelta(t, 160.0, forecast.Points[0].DiscretionaryExpenses, 1e-9)
+	assert.InDelta(t, -910.0, forecast.Points[0].Expected, 1e-9)
+	assert.InDelta(t, 170.0, forecast.Points[1].DiscretionaryExpenses, 1e-9)
+	assert.InDelta(t, -1080.0, forecast.Points[1].Expected, 1e-9)
+
+	// Точная прямая не дает разброса
+	for _, point := range forecast.Points {
+		assert.InDelta(t, point.Expected, point.Optimistic, 1e-6)
+		assert.InDelta(t, point.Expected, point.Pessimistic, 1e-6)
+	}
+}
+
+func TestGetForecastShortHistory(t *testing.T) {
+	today := forecastToday()
+
+	// Два месяца истории: прогноз по среднему без наклона, разброс копится по месяцам
+	transactions := []models.Transaction{
+		{ID: "1", Title: "Кафе", Category: "Еда", Amount: 100, Date: monthAgo(today, 1)},
+		{ID: "2", Title: "Кафе", Category: "Еда", Amount: 200, Date: monthAgo(today, 0)},
+		{ID: "3", Title: "Подработка", Category: "Фриланс", Amount: 400, Date: monthAgo(today, 0)},
+	}
+	income := map[string]struct{}{"Фриланс": {}}
+
+	forecast, err := newForecastService(t, transactions, income).GetForecast(context.Background(), 2)
+	require.NoError(t, err)
+
+	assert.InDelta(t, 100.0, forecast.StartBalance, 1e-9)
+	require.Len(t, forecast.Points, 2)
+
+	// Доход 0 и 400 по месяцам дает отклонение 282.84, траты 100 и 200 - 70.71
+	first := forecast.Points[0]
+	assert.InDelta(t, 200.0, first.DiscretionaryIncome, 1e-9)
+	assert.InDelta(t, 150.0, first.DiscretionaryExpenses, 1e-9)
+	assert.InDelta(t, 150.0, first.Expected, 1e-9)
+	assert.InDelta(t, 150.0+291.5475947, first.Optimistic, 1e-6)
+	assert.InDelta(t, 150.0-291.5475947, first.Pessimistic, 1e-6)
+
+	second := forecast.Points[1]
+	assert.InDelta(t, 200.0, second.Expected, 1e-9)
+	assert.InDelta(t, 200.0+412.3105626, second.Optimistic, 1e-6)
+	assert.InDelta(t, 200.0-412.3105626, second.Pessimistic, 1e-6)
+}
+
+func TestGetForecastErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionsProvider := mocks.NewMockTransactionsProvider(ctrl)
+	service := NewStatisticsService(transactionsProvider, mocks.NewMockCategoriesProvider(ctrl), mocks.NewMockPayeesProvider(ctrl))
+
+	// Неверный горизонт отклоняется до обращения к транзакциям
+	for _, months := range []int{0, models.MaxForecastMonths + 1} {
+		_, err := service.GetForecast(context.Background(), months)
+		assert.ErrorIs(t, err, models.ErrBadRequest)
+	}
+
+	errStorage := errors.New("storage is unavailable")
+	transactionsProvider.EXPECT().
+		GetAllTransactions(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, errStorage)
+
+	_, err := service.GetForecast(context.Background(), 1)
+	assert.ErrorIs(t, err, errStorage)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+
+	"spendings-backend/internal/models"
+)
+
+// maxIdempotencyKeyLength максимальная длина ключа идемпотентности
+const maxIdempotencyKeyLength = 255
+
+var (
+	errInvalidIdempotencyKey  = errors.New("invalid idempotency key")
+	errIdempotencyKeyReused   = errors.New("idempotency key was already used with a different request")
+	errIdempotencyKeyInFlight = errors.New("request with this idempotency key is still being processed")
+)
+
+// idempotencyRecord запрос, выполненный с ключом идемпотентности
+type idempotencyRecord struct {
+	// fingerprint хеш метода, пути и тела исходного запроса
+	fingerprint string
+	// response пустой, пока исходный запрос выполняется
+	response  *models.IdempotentResponse
+	expiresAt time.Time
+}
+
+// IdempotencyService хранит ответы на запросы с ключом идемпотентности,
+// чтобы повторы запроса не создавали новые записи
+type IdempotencyService struct {
+	ttl     time.Duration
+	records map[string]map[string]*idempotencyRecord // userID -> key -> record
+	mux     sync.Mutex
+	logger  *zap.SugaredLogger
+}
+
+// NewIdempotencyService создает новый сервис ключей идемпотентности
+func NewIdempotencyService(ttl time.Duration, logger *zap.SugaredLogger) *IdempotencyService {
+	return &IdempotencyService{
+		ttl:     ttl,
+		records: make(map[string]map[string]*idempotencyRecord),
+		logger:  logger,
+	}
+}
+
+// Begin регистрирует запрос с ключом. Если ключ уже использовался с тем же запросом,
+// возвращает сохраненный ответ. Если с другим запросом или исходный запрос еще
+// выполняется, возвращает ErrConflict. Если ответ nil, запрос нужно выполнить
+// и затем вызвать Complete или Release.
+func (is *IdempotencyService) Begin(ctx context.Context, key, fingerprint string) (*models.IdempotentResponse, error) {
+	if err := validateIdempotencyKey(key); err != nil {
+		return nil, err
+	}
+
+	userID := models.ClaimsFromContext(ctx).ID
+	now := time.Now()
+
+	is.mux.Lock()
+	defer is.mux.Unlock()
+
+	userRecords, exists := is.records[userID]
+	if !exists {
+		userRecords = make(map[string]*idempotencyRecord)
+		is.records[userID] = userRecords
+	}
+
+	if record, exists := userRecords[key]; exists && now.Before(record.expiresAt) {
+		switch {
+		case record.fingerprint != fingerprint:
+			return nil, fmt.Errorf("%w: %w", models.ErrConflict, errIdempotencyKeyReused)
+		case record.response == nil:
+			return nil, fmt.Errorf("%w: %w", models.ErrConflict, errIdempotencyKeyInFlight)
+		default:
+			return record.response, nil
+		}
+	}
+
+	userRecords[key] = &idempotencyRecord{
+		fingerprint: fingerprint,
+		expiresAt:   now.Add(is.ttl),
+	}
+
+	return nil, nil
+}
+
+// Complete сохраняет ответ на запрос, начатый через Begin
+func (is *IdempotencyService) Complete(ctx context.Context, key string, response models.IdempotentResponse) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	is.mux.Lock()
+	defer is.mux.Unlock()
+
+	if record, exists := is.records[userID][key]; exists {
+		record.response = &response
+	}
+}
+
+// Release освобождает ключ, если запрос завершился ошибкой сервера и его можно повторить
+func (is *IdempotencyService) Release(ctx context.Context, key string) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	is.mux.Lock()
+	defer is.mux.Unlock()
+
+	delete(is.records[userID], key)
+}
+
+// DeleteUserData удаляет ключи и сохраненные ответы пользователя
+func (is *IdempotencyService) DeleteUserData(userID string) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+
+	delete(is.records, userID)
+}
+
+// Start периодически удаляет просроченные ключи
+func (is *IdempotencyService) Start(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if removed := is.purgeExpired(time.Now()); removed > 0 {
+				is.logger.Infof("Removed %d expired idempotency keys", removed)
+			}
+		case <-ctx.Done():
+			is.logger.Info("Idempotency service stopped by context")
+			return
+		}
+	}
+}
+
+func (is *IdempotencyService) purgeExpired(now time.Time) int {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+
+	removed := 0
+	for userID, userRecords := range is.records {
+		for key, record := range userRecords {
+			if !now.Before(record.expiresAt) {
+				delete(userRecords, key)
+				removed++
+			}
+		}
+
+		if len(userRecords) == 0 {
+			delete(is.records, userID)
+		}
+	}
+
+	return removed
+}
+
+func validateIdempotencyKey(key string) error {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("%w: %w: length must be from 1 to %d", models.ErrBadRequest, errInvalidIdempotencyKey, maxIdempotencyKeyLength)
+	}
+
+	for _, r := range key {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("%w: %w: must contain only printable characters", models.ErrBadRequest, errInvalidIdempotencyKey)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"spendings-backend/internal/models"
+)
+
+// defaultImportCategory категория для импортированных транзакций без категории
+const defaultImportCategory = "Прочее"
+
+var (
+	errEmptyAmount   = errors.New("empty amount")
+	errInvalidAmount = errors.New("invalid amount")
+	errEmptyDate     = errors.New("empty date")
+
+	errInvalidDuplicatesMode = errors.New("invalid duplicates mode")
+)
+
+type TransactionsCreator interface {
+	CreateTransaction(ctx context.Context, req models.CreateTransactionRequest) (*models.CreateTransactionResponse, error)
+	ValidateTransaction(req models.CreateTransactionRequest) error
+	FindDuplicates(ctx context.Context, req models.CreateTransactionRequest) ([]string, error)
+}
+
+// importRow строка файла импорта после разбора. Если Err не пустая, строка не импортируется.
+type importRow struct {
+	Row     int
+	Request models.CreateTransactionRequest
+	// DefaultCategory категория списания не указана в файле и выбрана по умолчанию, ее можно заменить правилом
+	DefaultCategory bool
+	Err             error
+}
+
+// ImportService сервис импорта транзакций из банковских выписок
+type ImportService struct {
+	transactionsService TransactionsCreator
+	rules               RulesMatcher
+}
+
+// NewImportService создает новый сервис импорта
+func NewImportService(transactionsService TransactionsCreator, rules RulesMatcher) *ImportService {
+	return &ImportService{
+		transactionsService: transactionsService,
+		rules:               rules,
+	}
+}
+
+// importRows проверяет и сохраняет разобранные строки. Ошибка в одной строке
+// не прерывает импорт остальных. В режиме dryRun транзакции только проверяются.
+// Строки, совпадающие по отпечатку с уже сохраненными транзакциями, пропускаются
+// или помечаются в зависимости от options.Duplicates.
+func (is *ImportService) importRows(ctx context.Context, rows []importRow, options models.ImportOptions) *models.ImportResult {
+	result := &models.ImportResult{
+		DryRun:       options.DryRun,
+		Total:        len(rows),
+		Transactions: make([]models.ImportedTransaction, 0, len(rows)),
+		Errors:       make([]models.ImportRowError, 0),
+	}
+
+	// Каждая существующая транзакция может быть дубликатом только одной строки,
+	// а созданные в этом импорте транзакции дубликатами не считаются:
+	// две одинаковые покупки за день в одной выписке - не ошибка
+	matched := make(map[string]struct{})
+
+	for _, row := range rows {
+		if row.Err != nil {
+			addImportError(result, row.Row, row.Err)
+			continue
+		}
+
+		if row.DefaultCategory {
+			applyRule(ctx, is.rules, &row.Request)
+		}
+
+		if err := is.transactionsService.ValidateTransaction(row.Request); err != nil {
+			addImportError(result, row.Row, err)
+			continue
+		}
+
+		imported := models.ImportedTransaction{
+			Row:         row.Row,
+			Transaction: row.Request,
+		}
+
+		duplicateIDs, err := is.transactionsService.FindDuplicates(ctx, row.Request)
+		if err != nil {
+			addImportError(result, row.Row, err)
+			continue
+		}
+
+		for _, id := range duplicateIDs {
+			if _, exists := matched[id]; !exists {
+				matched[id] = struct{}{}
+				imported.DuplicateOf = id
+				break
+			}
+		}
+
+		if imported.DuplicateOf != "" {
+			result.Duplicates++
+
+			if options.Duplicates != models.DuplicatesFlag {
+				imported.Skipped = true
+				result.Transactions = append(result.Transactions, imported)
+				result.Skipped++
+				continue
+			}
+		}
+
+		if !options.DryRun {
+			response, err := is.transactionsService.CreateTransaction(ctx, row.Request)
+			if err != nil {
+				addImportError(result, row.Row, err)
+				continue
+			}
+			imported.ID = response.ID
+			matched[response.ID] = struct{}{}
+		}
+
+		result.Transactions = append(result.Transactions, imported)
+		result.Imported++
+	}
+
+	return result
+}
+
+// validateImportOptions проверяет общие параметры импорта
+func validateImportOptions(options models.ImportOptions) error {
+	switch options.Duplicates {
+	case "", models.DuplicatesSkip, models.DuplicatesFlag:
+		return nil
+	default:
+		return fmt.Errorf("%w: %w: %s, must be one of: skip, flag", models.ErrBadRequest, errInvalidDuplicatesMode, options.Duplicates)
+	}
+}
+
+// parseAmount разбирает сумму из выписки: допускает десятичную запятую,
+// пробелы между разрядами и обозначение валюты. Возвращает модуль суммы
+// и признак того, что сумма была отрицательной.
+func parseAmount(value string) (float64, bool, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, false, errEmptyAmount
+	}
+
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == ',', r == '.', r == '-':
+			return r
+		default:
+			return -1
+		}
+	}, value)
+
+	if cleaned == "" {
+		return 0, false, fmt.Errorf("%w: %q", errInvalidAmount, value)
+	}
+
+	// Десятичный разделитель - последний из встретившихся; остальные разделители считаем разрядными
+	decimalIndex := strings.LastIndexAny(cleaned, ",.")
+	if decimalIndex >= 0 {
+		integerPart := strings.NewReplacer(",", "", ".", "").Replace(cleaned[:decimalIndex])
+		cleaned = integerPart + "." + cleaned[decimalIndex+1:]
+	}
+
+	amount, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("%w %q: %w", errInvalidAmount, value, err)
+	}
+
+	if amount < 0 {
+		return -amount, true, nil
+	}
+
+	return amount, false, nil
+}
+
+// dateLayout переводит формат вида "DD.MM.YYYY" в формат Go.
+// Если формат уже задан в нотации Go, он возвращается без изменений.
+func dateLayout(format string) string {
+	if format == "" {
+		return "2006-01-02"
+	}
+
+	return strings.NewReplacer(
+		"YYYY", "2006",
+		"YY", "06",
+		"MM", "01",
+		"DD", "02",
+		"hh", "15",
+		"HH", "15",
+		"mm", "04",
+		"ss", "05",
+	).Replace(format)
+}
+
+// parseDate разбирает дату в заданном формате и возвращает ее в формате YYYY-MM-DD
+func parseDate(value, layout string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errEmptyDate
+	}
+
+	date, err := time.Parse(layout, value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q: %w", value, err)
+	}
+
+	return date.Format("2006-01-02"), nil
+}
+
+func addImportError(result *models.ImportResult, row int, err error) {
+	result.Errors = append(result.Errors, models.ImportRowError{
+		Row:   row,
+		Error: err.Error(),
+	})
+	result.Failed++
+}
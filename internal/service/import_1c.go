@@ -0,0 +1,162 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"spendings-backend/internal/models"
+)
+
+const (
+	oneCHeader        = "1CClientBankExchange"
+	oneCDocumentStart = "СекцияДокумент"
+	oneCDocumentEnd   = "КонецДокумента"
+	oneCDateLayout    = "02.01.2006"
+)
+
+var errNot1CFile = errors.New("file is not in 1CClientBankExchange format")
+
+// windows1251High символы Windows-1251 в диапазоне 0x80-0xBF.
+// Байты 0xC0-0xFF соответствуют буквам А-я подряд.
+var windows1251High = [64]rune{
+	'Ђ', 'Ѓ', '‚', 'ѓ', '„', '…', '†', '‡', '€', '‰', 'Љ', '‹', 'Њ', 'Ќ', 'Ћ', 'Џ',
+	'ђ', '‘', '’', '“', '”', '•', '–', '—', utf8.RuneError, '™', 'љ', '›', 'њ', 'ќ', 'ћ', 'џ',
+	'\u00a0', 'Ў', 'ў', 'Ј', '¤', 'Ґ', '¦', '§', 'Ё', '©', 'Є', '«', '¬', '\u00ad', '®', 'Ї',
+	'°', '±', 'І', 'і', 'ґ', 'µ', '¶', '·', 'ё', '№', 'є', '»', 'ј', 'Ѕ', 'ѕ', 'ї',
+}
+
+// oneCDocument поля платежного документа
+type oneCDocument struct {
+	line   int
+	fields map[string]string
+}
+
+// parse1C разбирает файл обмена с банком 1CClientBankExchange.
+// Файлы в кодировке Windows-1251 перекодируются автоматически.
+// Направление платежа определяется по расчетным счетам из заголовка файла.
+func parse1C(reader io.Reader) ([]importRow, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't read file: %w", err)
+	}
+
+	if !utf8.Valid(content) {
+		content = decodeWindows1251(content)
+	}
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	var (
+		rows       []importRow
+		accounts   = make(map[string]struct{})
+		document   *oneCDocument
+		lineNumber = 0
+	)
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if lineNumber == 1 {
+			if line != oneCHeader {
+				return nil, errNot1CFile
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+
+		switch {
+		case key == oneCDocumentStart:
+			document = &oneCDocument{line: lineNumber, fields: make(map[string]string)}
+		case key == oneCDocumentEnd:
+			if document != nil {
+				rows = append(rows, oneCDocumentRow(document, accounts))
+			}
+			document = nil
+		case document != nil:
+			document.fields[key] = value
+		case key == "РасчСчет":
+			accounts[value] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read file: %w", err)
+	}
+
+	if lineNumber == 0 {
+		return nil, errNot1CFile
+	}
+
+	return rows, nil
+}
+
+func oneCDocumentRow(document *oneCDocument, accounts map[string]struct{}) importRow {
+	row := importRow{Row: document.line}
+
+	date, err := parseDate(document.fields["Дата"], oneCDateLayout)
+	if err != nil {
+		row.Err = err
+		return row
+	}
+
+	// Направление платежа в 1С задается счетами, а не знаком суммы
+	amount, _, err := parseAmount(document.fields["Сумма"])
+	if err != nil {
+		row.Err = err
+		return row
+	}
+
+	// Поступление - если получатель один из счетов выписки
+	_, isIncome := accounts[document.fields["ПолучательСчет"]]
+
+	title := document.fields["Получатель"]
+	if title == "" {
+		title = document.fields["Получатель1"]
+	}
+	if isIncome {
+		title = document.fields["Плательщик"]
+		if title == "" {
+			title = document.fields["Плательщик1"]
+		}
+	}
+	if title == "" {
+		title = document.fields["НазначениеПлатежа"]
+	}
+
+	row.Request = models.CreateTransactionRequest{
+		Amount:   amount,
+		Title:    title,
+		Category: statementCategory(isIncome),
+		Date:     date,
+	}
+	// Поступления уже в категории доходов, правила меняют только категорию списаний
+	row.DefaultCategory = !isIncome
+
+	return row
+}
+
+// decodeWindows1251 перекодирует текст из Windows-1251 в UTF-8
+func decodeWindows1251(content []byte) []byte {
+	decoded := make([]byte, 0, len(content)*2)
+
+	for _, b := range content {
+		switch {
+		case b < 0x80:
+			decoded = append(decoded, b)
+		case b < 0xC0:
+			decoded = utf8.AppendRune(decoded, windows1251High[b-0x80])
+		default:
+			decoded = utf8.AppendRune(decoded, rune('А')+rune(b-0xC0))
+		}
+	}
+
+	return decoded
+}
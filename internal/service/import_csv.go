@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errColumnNotFound   = errors.New("column not found")
+	errRequiredColumn   = errors.New("column mapping is required")
+	errColumnOutOfRange = errors.New("column is out of range")
+)
+
+// csvColumns номера столбцов (с 0) для полей транзакции; -1 - столбец не задан
+type csvColumns struct {
+	date     int
+	amount   int
+	title    int
+	category int
+}
+
+// ImportCSV импортирует транзакции из CSV-выписки с заданным сопоставлением столбцов
+func (is *ImportService) ImportCSV(ctx context.Context, reader io.Reader, options models.CSVImportOptions, importOptions models.ImportOptions) (*models.ImportResult, error) {
+	if err := validateImportOptions(importOptions); err != nil {
+		return nil, err
+	}
+
+	if options.DateColumn == "" || options.AmountColumn == "" || options.TitleColumn == "" {
+		return nil, fmt.Errorf("%w: %w: date, amount and title", models.ErrBadRequest, errRequiredColumn)
+	}
+
+	rows, err := parseCSV(reader, options)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, err)
+	}
+
+	return is.importRows(ctx, rows, importOptions), nil
+}
+
+// parseCSV разбирает CSV-выписку на строки импорта по сопоставлению столбцов.
+// Ошибка возвращается, только если не удалось прочитать заголовок или найти столбцы.
+func parseCSV(reader io.Reader, options models.CSVImportOptions) ([]importRow, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = options.Delimiter
+	if csvReader.Comma == 0 {
+		csvReader.Comma = ';'
+	}
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	var header []string
+	if options.HasHeader {
+		record, err := csvReader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("can't read csv header: %w", err)
+		}
+		header = record
+		if len(header) > 0 {
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		}
+	}
+
+	columns, err := resolveCSVColumns(header, options)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultCategory := options.DefaultCategory
+	if defaultCategory == "" {
+		defaultCategory = defaultImportCategory
+	}
+
+	layout := dateLayout(options.DateFormat)
+
+	var (
+		rows []importRow
+		// credits строки с положительной суммой без категории в файле
+		credits []int
+		signed  bool
+	)
+	rowNumber := 0
+	if options.HasHeader {
+		rowNumber = 1
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		rowNumber++
+
+		if err != nil {
+			rows = append(rows, importRow{Row: rowNumber, Err: err})
+			continue
+		}
+
+		// Пустые строки в конце выписки пропускаем
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		row, negative := parseCSVRecord(rowNumber, record, columns, layout, defaultCategory)
+		if row.Err == nil && row.DefaultCategory {
+			if negative {
+				signed = true
+			} else if row.Request.Amount != 0 {
+				credits = append(credits, len(rows))
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	// В выписке со знаком списания отрицательные, а положительные суммы - поступления.
+	// Если отрицательных сумм нет, все строки считаются списаниями.
+	if signed {
+		for _, i := range credits {
+			rows[i].Request.Category = statementCategory(true)
+			rows[i].DefaultCategory = false
+		}
+	}
+
+	return rows, nil
+}
+
+// parseCSVRecord разбирает строку CSV и возвращает признак отрицательной суммы
+func parseCSVRecord(rowNumber int, record []string, columns csvColumns, layout, defaultCategory string) (importRow, bool) {
+	row := importRow{Row: rowNumber}
+
+	field := func(column int) (string, error) {
+		if column < 0 {
+			return "", nil
+		}
+		if column >= len(record) {
+			return "", fmt.Errorf("%w: %d", errColumnOutOfRange, column+1)
+		}
+		return strings.TrimSpace(record[column]), nil
+	}
+
+	dateValue, err := field(columns.date)
+	if err != nil {
+		row.Err = err
+		return row, false
+	}
+
+	amountValue, err := field(columns.amount)
+	if err != nil {
+		row.Err = err
+		return row, false
+	}
+
+	title, err := field(columns.title)
+	if err != nil {
+		row.Err = err
+		return row, false
+	}
+
+	category, err := field(columns.category)
+	if err != nil {
+		row.Err = err
+		return row, false
+	}
+
+	date, err := parseDate(dateValue, layout)
+	if err != nil {
+		row.Err = err
+		return row, false
+	}
+
+	amount, negative, err := parseAmount(amountValue)
+	if err != nil {
+		row.Err = err
+		return row, false
+	}
+
+	if category == "" {
+		category = defaultCategory
+		row.DefaultCategory = true
+	}
+
+	row.Request = models.CreateTransactionRequest{
+		Amount:   amount,
+		Title:    title,
+		Category: category,
+		Date:     date,
+	}
+
+	return row, negative
+}
+
+// resolveCSVColumns находит номера столбцов по названиям из заголовка или по номерам
+func resolveCSVColumns(header []string, options models.CSVImportOptions) (csvColumns, error) {
+	resolve := func(column string) (int, error) {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			return -1, nil
+		}
+
+		if number, err := strconv.Atoi(column); err == nil {
+			if number < 1 {
+				return 0, fmt.Errorf("%w: %d", errColumnOutOfRange, number)
+			}
+			return number - 1, nil
+		}
+
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				return i, nil
+			}
+		}
+
+		return 0, fmt.Errorf("%w: %s", errColumnNotFound, column)
+	}
+
+	var (
+		columns csvColumns
+		err     error
+	)
+
+	if columns.date, err = resolve(options.DateColumn); err != nil {
+		return csvColumns{}, err
+	}
+	if columns.amount, err = resolve(options.AmountColumn); err != nil {
+		return csvColumns{}, err
+	}
+	if columns.title, err = resolve(options.TitleColumn); err != nil {
+		return csvColumns{}, err
+	}
+	if columns.category, err = resolve(options.CategoryColumn); err != nil {
+		return csvColumns{}, err
+	}
+
+	return columns, nil
+}
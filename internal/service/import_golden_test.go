@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"spendings-backend/internal/models"
+)
+
+// updateGolden перезаписывает эталонные файлы: go test ./internal/service -run Golden -update
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// goldenRow строка импорта в эталонном файле, ошибка хранится текстом
+type goldenRow struct {
+	Row             int                             `json:"row"`
+	Request         models.CreateTransactionRequest `json:"request"`
+	DefaultCategory bool                            `json:"defaultCategory"`
+	Err             string                          `json:"error,omitempty"`
+}
+
+func TestParsersGolden(t *testing.T) {
+	csvParser := func(options models.CSVImportOptions) statementParser {
+		return func(reader io.Reader) ([]importRow, error) {
+			return parseCSV(reader, options)
+		}
+	}
+
+	cases := []struct {
+		input  string
+		golden string
+		parse  statementParser
+	}{
+		{
+			input: "signed.csv",
+			parse: csvParser(models.CSVImportOptions{
+				HasHeader:      true,
+				DateFormat:     "DD.MM.YYYY",
+				DateColumn:     "Дата",
+				AmountColumn:   "Сумма",
+				TitleColumn:    "Описание",
+				CategoryColumn: "Категория",
+			}),
+		},
+		{
+			input: "unsigned.csv",
+			parse: csvParser(models.CSVImportOptions{
+				Delimiter:       ',',
+				DateColumn:      "1",
+				AmountColumn:    "2",
+				TitleColumn:     "3",
+				DefaultCategory: "Покупки",
+			}),
+		},
+		{input: "sgml.ofx", parse: parseOFX},
+		{input: "xml.ofx", parse: parseOFX},
+		{input: "bank.qif", parse: parseQIF},
+		// Выписка в UTF-8 и та же выписка в Windows-1251 дают одинаковые строки
+		{input: "utf8.1c.txt", golden: "statement.1c", parse: parse1C},
+		{input: "windows1251.1c.txt", golden: "statement.1c", parse: parse1C},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			input, err := os.Open(filepath.Join("testdata", "import", tc.input))
+			require.NoError(t, err)
+			defer input.Close()
+
+			rows, err := tc.parse(input)
+			require.NoError(t, err)
+
+			got := make([]goldenRow, 0, len(rows))
+			for _, row := range rows {
+				golden := goldenRow{Row: row.Row, Request: row.Request, DefaultCategory: row.DefaultCategory}
+				if row.Err != nil {
+					golden.Err = row.Err.Error()
+				}
+				got = append(got, golden)
+			}
+
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			require.NoError(t, encoder.Encode(got))
+
+			name := tc.golden
+			if name == "" {
+				name = tc.input
+			}
+			goldenPath := filepath.Join("testdata", "import", name+".golden.json")
+
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(goldenPath, buf.Bytes(), 0o644))
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), buf.String())
+		})
+	}
+}
+
+func TestParseStatementErrors(t *testing.T) {
+	_, err := parseOFX(bytes.NewReader([]byte("OFXHEADER:100\n")))
+	assert.ErrorIs(t, err, errNoOFXBody)
+
+	_, err = parse1C(bytes.NewReader([]byte("Не выписка\n")))
+	assert.ErrorIs(t, err, errNot1CFile)
+
+	_, err = parseCSV(bytes.NewReader([]byte("Дата;Сумма\n")), models.CSVImportOptions{
+		HasHeader:    true,
+		DateColumn:   "Дата",
+		AmountColumn: "Сумма",
+		TitleColumn:  "Описание",
+	})
+	assert.ErrorIs(t, err, errColumnNotFound)
+}
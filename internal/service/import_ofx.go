@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errNoOFXBody     = errors.New("OFX body not found")
+	errOFXUnexpected = errors.New("unexpected end of OFX tag")
+)
+
+// ofxTag открывающий или закрывающий тег OFX со значением, идущим за ним
+type ofxTag struct {
+	name    string
+	closing bool
+	value   string
+}
+
+// parseOFX разбирает выписку OFX. Поддерживаются как SGML (OFX 1.x), где
+// у элементов со значением нет закрывающих тегов, так и XML (OFX 2.x).
+func parseOFX(reader io.Reader) ([]importRow, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't read file: %w", err)
+	}
+
+	body := string(content)
+	start := strings.Index(strings.ToUpper(body), "<OFX>")
+	if start < 0 {
+		return nil, errNoOFXBody
+	}
+
+	tags, err := tokenizeOFX(body[start:])
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		rows    []importRow
+		current map[string]string
+	)
+
+	for _, tag := range tags {
+		switch {
+		case tag.name == "STMTTRN" && !tag.closing:
+			current = make(map[string]string)
+		case tag.name == "STMTTRN" && tag.closing:
+			if current != nil {
+				rows = append(rows, ofxTransactionRow(len(rows)+1, current))
+			}
+			current = nil
+		case current != nil && !tag.closing && tag.value != "":
+			current[tag.name] = tag.value
+		}
+	}
+
+	return rows, nil
+}
+
+func tokenizeOFX(body string) ([]ofxTag, error) {
+	var tags []ofxTag
+
+	for {
+		open := strings.IndexByte(body, '<')
+		if open < 0 {
+			return tags, nil
+		}
+
+		closeIndex := strings.IndexByte(body[open:], '>')
+		if closeIndex < 0 {
+			return nil, errOFXUnexpected
+		}
+
+		name := body[open+1 : open+closeIndex]
+		body = body[open+closeIndex+1:]
+
+		value := body
+		if next := strings.IndexByte(body, '<'); next >= 0 {
+			value = body[:next]
+		}
+
+		tag := ofxTag{
+			name:  strings.ToUpper(strings.TrimPrefix(name, "/")),
+			value: html.UnescapeString(strings.TrimSpace(value)),
+		}
+		tag.closing = strings.HasPrefix(name, "/")
+
+		tags = append(tags, tag)
+	}
+}
+
+func ofxTransactionRow(rowNumber int, fields map[string]string) importRow {
+	row := importRow{Row: rowNumber}
+
+	date, err := parseOFXDate(fields["DTPOSTED"])
+	if err != nil {
+		row.Err = err
+		return row
+	}
+
+	amountValue := fields["TRNAMT"]
+	amount, negative, err := parseAmount(amountValue)
+	if err != nil {
+		row.Err = err
+		return row
+	}
+
+	title := fields["NAME"]
+	if title == "" {
+		title = fields["PAYEE"]
+	}
+	if title == "" {
+		title = fields["MEMO"]
+	}
+
+	isIncome := !negative && amount != 0
+	if trnType := strings.ToUpper(fields["TRNTYPE"]); trnType == "DEBIT" || trnType == "PAYMENT" || trnType == "FEE" {
+		isIncome = false
+	}
+
+	row.Request = models.CreateTransactionRequest{
+		Amount:   amount,
+		Title:    title,
+		Category: statementCategory(isIncome),
+		Date:     date,
+	}
+	// Поступления уже в категории доходов, правила меняют только категорию списаний
+	row.DefaultCategory = !isIncome
+
+	return row
+}
+
+// parseOFXDate разбирает дату вида YYYYMMDD[HHMMSS[.XXX]][[+-]HH[.MM]:TZ],
+// сохраняя календарную дату без перевода часового пояса
+func parseOFXDate(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errEmptyDate
+	}
+
+	if len(value) < len("20060102") {
+		return "", fmt.Errorf("invalid date %q", value)
+	}
+
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q: %w", value, err)
+	}
+
+	return date.Format("2006-01-02"), nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"spendings-backend/internal/models"
+)
+
+// qifRecord поля одной записи QIF до разбора
+type qifRecord struct {
+	// line номер строки, с которой начинается запись
+	line   int
+	fields map[byte]string
+}
+
+// parseQIF разбирает выписку QIF. Учитываются только записи банковских
+// и карточных счетов, списки категорий и счетов пропускаются.
+func parseQIF(reader io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(reader)
+
+	var (
+		rows          []importRow
+		current       *qifRecord
+		inTransaction = true
+		lineNumber    = 0
+	)
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			header := strings.ToLower(strings.TrimSpace(line))
+			inTransaction = strings.HasPrefix(header, "!type:") &&
+				!strings.HasPrefix(header, "!type:cat") &&
+				!strings.HasPrefix(header, "!type:class") &&
+				!strings.HasPrefix(header, "!type:memorized")
+			current = nil
+			continue
+		}
+
+		if !inTransaction {
+			continue
+		}
+
+		if line[0] == '^' {
+			if current != nil {
+				rows = append(rows, qifTransactionRow(current))
+			}
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			current = &qifRecord{line: lineNumber, fields: make(map[byte]string)}
+		}
+
+		// Первое вхождение поля важнее: в разбиениях (S, E, $) поля повторяются
+		if _, exists := current.fields[line[0]]; !exists {
+			current.fields[line[0]] = strings.TrimSpace(line[1:])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read file: %w", err)
+	}
+
+	// Последняя запись может быть не завершена символом ^
+	if current != nil {
+		rows = append(rows, qifTransactionRow(current))
+	}
+
+	return rows, nil
+}
+
+func qifTransactionRow(record *qifRecord) importRow {
+	row := importRow{Row: record.line}
+
+	date, err := parseQIFDate(record.fields['D'])
+	if err != nil {
+		row.Err = err
+		return row
+	}
+
+	amountValue := record.fields['T']
+	if amountValue == "" {
+		amountValue = record.fields['U']
+	}
+
+	amount, negative, err := parseAmount(amountValue)
+	if err != nil {
+		row.Err = err
+		return row
+	}
+
+	title := record.fields['P']
+	if title == "" {
+		title = record.fields['M']
+	}
+
+	isIncome := !negative && amount != 0
+	category := statementCategory(isIncome)
+	// Поступления уже в категории доходов, правила меняют только категорию списаний
+	row.DefaultCategory = !isIncome
+
+	// Категория QIF; в квадратных скобках указываются переводы между счетами
+	if qifCategory := record.fields['L']; qifCategory != "" && !strings.HasPrefix(qifCategory, "[") && !isIncome {
+		category = qifCategory
+		row.DefaultCategory = false
+	}
+
+	row.Request = models.CreateTransactionRequest{
+		Amount:   amount,
+		Title:    title,
+		Category: category,
+		Date:     date,
+	}
+
+	return row
+}
+
+// parseQIFDate разбирает дату QIF. Даты через точку читаются как ДД.ММ.ГГГГ,
+// через косую черту - как ММ/ДД/ГГГГ, через дефис - как ГГГГ-ММ-ДД.
+// Апостроф перед годом (1/31'26) и двузначный год допускаются.
+func parseQIFDate(value string) (string, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	if value == "" {
+		return "", errEmptyDate
+	}
+
+	normalized := strings.ReplaceAll(value, "'", "/")
+
+	var separator string
+	for _, candidate := range []string{".", "/", "-"} {
+		if strings.Contains(normalized, candidate) {
+			separator = candidate
+			break
+		}
+	}
+	if separator == "" {
+		return "", fmt.Errorf("invalid date %q", value)
+	}
+
+	parts := strings.Split(normalized, separator)
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid date %q", value)
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return "", fmt.Errorf("invalid date %q: %w", value, err)
+		}
+		numbers[i] = number
+	}
+
+	var year, month, day int
+	switch separator {
+	case ".":
+		day, month, year = numbers[0], numbers[1], numbers[2]
+	case "/":
+		month, day, year = numbers[0], numbers[1], numbers[2]
+	default:
+		year, month, day = numbers[0], numbers[1], numbers[2]
+	}
+
+	if year < 100 {
+		year += 2000
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day || int(date.Month()) != month {
+		return "", fmt.Errorf("invalid date %q", value)
+	}
+
+	return date.Format("2006-01-02"), nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"spendings-backend/internal/models"
+)
+
+// Форматы выписок, которые импортируются без настройки столбцов
+const (
+	StatementFormatOFX = "ofx"
+	StatementFormatQIF = "qif"
+	StatementFormat1C  = "1c"
+)
+
+var errUnknownStatementFormat = errors.New("unknown statement format")
+
+// statementParser разбирает файл выписки на строки импорта.
+// Ошибка возвращается, только если файл не удалось разобрать целиком.
+type statementParser func(reader io.Reader) ([]importRow, error)
+
+var statementParsers = map[string]statementParser{
+	StatementFormatOFX: parseOFX,
+	StatementFormatQIF: parseQIF,
+	StatementFormat1C:  parse1C,
+}
+
+// ImportStatement импортирует транзакции из выписки в формате OFX, QIF или 1CClientBankExchange.
+// Поступления сохраняются в категорию доходов, списания - в категорию по умолчанию.
+func (is *ImportService) ImportStatement(ctx context.Context, format string, reader io.Reader, options models.ImportOptions) (*models.ImportResult, error) {
+	if err := validateImportOptions(options); err != nil {
+		return nil, err
+	}
+
+	parse, exists := statementParsers[format]
+	if !exists {
+		return nil, fmt.Errorf("%w: %w: %s, must be one of: ofx, qif, 1c", models.ErrBadRequest, errUnknownStatementFormat, format)
+	}
+
+	rows, err := parse(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't parse %s statement: %w", models.ErrBadRequest, format, err)
+	}
+
+	return is.importRows(ctx, rows, options), nil
+}
+
+// statementCategory возвращает категорию транзакции по направлению платежа
+func statementCategory(isIncome bool) string {
+	if isIncome {
+		return models.IncomeCategory
+	}
+
+	return defaultImportCategory
+}
@@ -0,0 +1,166 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: statistics.go
+//
+// Generated by this command:
+//
+//	mockgen -source=statistics.go -destination=mocks/statistics.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	models "spendings-backend/internal/models"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTransactionsProvider is a mock of TransactionsProvider interface.
+type MockTransactionsProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransactionsProviderMockRecorder
+	isgomock struct{}
+}
+
+// MockTransactionsProviderMockRecorder is the mock recorder for MockTransactionsProvider.
+type MockTransactionsProviderMockRecorder struct {
+	mock *MockTransactionsProvider
+}
+
+// NewMockTransactionsProvider creates a new mock instance.
+func NewMockTransactionsProvider(ctrl *gomock.Controller) *MockTransactionsProvider {
+	mock := &MockTransactionsProvider{ctrl: ctrl}
+	mock.recorder = &MockTransactionsProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransactionsProvider) EXPECT() *MockTransactionsProviderMockRecorder {
+	return m.recorder
+}
+
+// GetAllTransactions mocks base method.
+func (m *MockTransactionsProvider) GetAllTransactions(ctx context.Context, fromDate, toDate time.Time) ([]models.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllTransactions", ctx, fromDate, toDate)
+	ret0, _ := ret[0].([]models.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllTransactions indicates an expected call of GetAllTransactions.
+func (mr *MockTransactionsProviderMockRecorder) GetAllTransactions(ctx, fromDate, toDate any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllTransactions", reflect.TypeOf((*MockTransactionsProvider)(nil).GetAllTransactions), ctx, fromDate, toDate)
+}
+
+// MockCategoriesProvider is a mock of CategoriesProvider interface.
+type MockCategoriesProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockCategoriesProviderMockRecorder
+	isgomock struct{}
+}
+
+// MockCategoriesProviderMockRecorder is the mock recorder for MockCategoriesProvider.
+type MockCategoriesProviderMockRecorder struct {
+	mock *MockCategoriesProvider
+}
+
+// NewMockCategoriesProvider creates a new mock instance.
+func NewMockCategoriesProvider(ctrl *gomock.Controller) *MockCategoriesProvider {
+	mock := &MockCategoriesProvider{ctrl: ctrl}
+	mock.recorder = &MockCategoriesProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCategoriesProvider) EXPECT() *MockCategoriesProviderMockRecorder {
+	return m.recorder
+}
+
+// GetCategoryNames mocks base method.
+func (m *MockCategoriesProvider) GetCategoryNames(ctx context.Context) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategoryNames", ctx)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategoryNames indicates an expected call of GetCategoryNames.
+func (mr *MockCategoriesProviderMockRecorder) GetCategoryNames(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryNames", reflect.TypeOf((*MockCategoriesProvider)(nil).GetCategoryNames), ctx)
+}
+
+// GetCategoryParents mocks base method.
+func (m *MockCategoriesProvider) GetCategoryParents(ctx context.Context) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategoryParents", ctx)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategoryParents indicates an expected call of GetCategoryParents.
+func (mr *MockCategoriesProviderMockRecorder) GetCategoryParents(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryParents", reflect.TypeOf((*MockCategoriesProvider)(nil).GetCategoryParents), ctx)
+}
+
+// GetIncomeCategories mocks base method.
+func (m *MockCategoriesProvider) GetIncomeCategories(ctx context.Context) (map[string]struct{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIncomeCategories", ctx)
+	ret0, _ := ret[0].(map[string]struct{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIncomeCategories indicates an expected call of GetIncomeCategories.
+func (mr *MockCategoriesProviderMockRecorder) GetIncomeCategories(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIncomeCategories", reflect.TypeOf((*MockCategoriesProvider)(nil).GetIncomeCategories), ctx)
+}
+
+// MockPayeesProvider is a mock of PayeesProvider interface.
+type MockPayeesProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockPayeesProviderMockRecorder
+	isgomock struct{}
+}
+
+// MockPayeesProviderMockRecorder is the mock recorder for MockPayeesProvider.
+type MockPayeesProviderMockRecorder struct {
+	mock *MockPayeesProvider
+}
+
+// NewMockPayeesProvider creates a new mock instance.
+func NewMockPayeesProvider(ctrl *gomock.Controller) *MockPayeesProvider {
+	mock := &MockPayeesProvider{ctrl: ctrl}
+	mock.recorder = &MockPayeesProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPayeesProvider) EXPECT() *MockPayeesProviderMockRecorder {
+	return m.recorder
+}
+
+// GetPayees mocks base method.
+func (m *MockPayeesProvider) GetPayees(ctx context.Context) ([]models.Payee, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayees", ctx)
+	ret0, _ := ret[0].([]models.Payee)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayees indicates an expected call of GetPayees.
+func (mr *MockPayeesProviderMockRecorder) GetPayees(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayees", reflect.TypeOf((*MockPayeesProvider)(nil).GetPayees), ctx)
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errEmptyPayeeName    = errors.New("payee name cannot be empty")
+	errInvalidPayeeAlias = errors.New("alias must contain letters or digits")
+	errPayeeAliasTaken   = errors.New("alias already belongs to another payee")
+)
+
+// payeeTranslit транслитерация кириллицы, близкая к написанию магазинов в банковских выписках
+var payeeTranslit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch",
+	'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// payeeNoiseWords слова, которые банки добавляют к названию магазина: города, страны
+// и организационно-правовые формы. Указаны после транслитерации.
+var payeeNoiseWords = map[string]struct{}{
+	"moskva": {}, "moscow": {}, "msk": {}, "spb": {}, "sankt": {}, "peterburg": {},
+	"petersburg": {}, "saint": {}, "st": {}, "rus": {}, "russia": {}, "rossiya": {},
+	"ru": {}, "g": {}, "ooo": {}, "oao": {}, "zao": {}, "pao": {}, "ao": {}, "ip": {},
+	"llc": {}, "ltd": {},
+}
+
+// normalizePayee приводит название транзакции к ключу получателя: латиница в нижнем
+// регистре без номеров магазинов, городов и организационно-правовых форм.
+// Так "PYATEROCHKA 1234 MOSKVA" и "Пятёрочка" дают один ключ "pyaterochka".
+func normalizePayee(title string) string {
+	words := make([]string, 0)
+	for _, token := range tokenize(title) {
+		var word strings.Builder
+		hasLetters := false
+		for _, r := range token {
+			if latin, exists := payeeTranslit[r]; exists {
+				word.WriteString(latin)
+				hasLetters = true
+				continue
+			}
+			if r >= 'a' && r <= 'z' {
+				hasLetters = true
+			}
+			word.WriteRune(r)
+		}
+
+		// Номера магазинов, терминалов и карт не относятся к названию
+		if !hasLetters {
+			continue
+		}
+		if _, noise := payeeNoiseWords[word.String()]; noise {
+			continue
+		}
+		words = append(words, word.String())
+	}
+
+	return strings.Join(words, " ")
+}
+
+// payeeMatcher сопоставляет названия транзакций с получателями пользователя
+type payeeMatcher struct {
+	payees map[string]models.Payee // ключ названия или псевдонима -> получатель
+}
+
+func newPayeeMatcher(payees []models.Payee) *payeeMatcher {
+	pm := &payeeMatcher{payees: make(map[string]models.Payee)}
+	for _, payee := range payees {
+		for _, key := range payeeKeys(payee) {
+			pm.payees[key] = payee
+		}
+	}
+
+	return pm
+}
+
+// match возвращает ключ получателя для названия транзакции. Получатель пользователя
+// подходит, если ключ названия начинается с его ключа целыми словами, при нескольких
+// подходящих выбирается самый длинный. Иначе ключом служит само нормализованное название.
+func (pm *payeeMatcher) match(title string) (string, *models.Payee) {
+	key := normalizePayee(title)
+	if key == "" {
+		return strings.ToLower(strings.TrimSpace(title)), nil
+	}
+
+	words := strings.Fields(key)
+	for n := len(words); n > 0; n-- {
+		if payee, exists := pm.payees[strings.Join(words[:n], " ")]; exists {
+			return payee.ID, &payee
+		}
+	}
+
+	return key, nil
+}
+
+// payeeKeys возвращает ключи названия и псевдонимов получателя без повторов
+func payeeKeys(payee models.Payee) []string {
+	keys := make([]string, 0, len(payee.Aliases)+1)
+	for _, name := range slices.Concat([]string{payee.Name}, payee.Aliases) {
+		if key := normalizePayee(name); key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// PayeesService хранит получателей платежей пользователей
+type PayeesService struct {
+	payees map[string][]models.Payee // userID -> получатели
+	mux    sync.RWMutex
+}
+
+func NewPayeesService(initialData map[string][]models.Payee) *PayeesService {
+	ps := &PayeesService{
+		payees: make(map[string][]models.Payee, len(initialData)),
+	}
+
+	for userID, payees := range initialData {
+		ps.payees[userID] = slices.Clone(payees)
+	}
+
+	return ps
+}
+
+// GetPayees возвращает получателей пользователя
+func (ps *PayeesService) GetPayees(ctx context.Context) ([]models.Payee, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	ps.mux.RLock()
+	defer ps.mux.RUnlock()
+
+	return backupPayees(ps.payees[userID]), nil
+}
+
+// CreatePayee добавляет получателя пользователя
+func (ps *PayeesService) CreatePayee(ctx context.Context, payee models.Payee) (*models.Payee, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	payee.ID = uuid.New().String()
+	payee, err := normalizePayeeRequest(payee)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, err)
+	}
+
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	if err := ps.checkPayeeKeys(userID, payee); err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, err)
+	}
+	ps.payees[userID] = append(ps.payees[userID], payee)
+
+	created := copyPayee(payee)
+
+	return &created, nil
+}
+
+// UpdatePayee заменяет название и псевдонимы получателя
+func (ps *PayeesService) UpdatePayee(ctx context.Context, id string, payee models.Payee) (*models.Payee, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	payee.ID = id
+	payee, err := normalizePayeeRequest(payee)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, err)
+	}
+
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	index := ps.payeeIndex(userID, id)
+	if index == -1 {
+		return nil, fmt.Errorf("%w: payee %s not found", models.ErrNotFound, id)
+	}
+	if err := ps.checkPayeeKeys(userID, payee); err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, err)
+	}
+	ps.payees[userID][index] = payee
+
+	updated := copyPayee(payee)
+
+	return &updated, nil
+}
+
+// DeletePayee удаляет получателя, его транзакции снова группируются по названиям
+func (ps *PayeesService) DeletePayee(ctx context.Context, id string) error {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	index := ps.payeeIndex(userID, id)
+	if index == -1 {
+		return fmt.Errorf("%w: payee %s not found", models.ErrNotFound, id)
+	}
+	ps.payees[userID] = slices.Delete(ps.payees[userID], index, index+1)
+
+	return nil
+}
+
+// payeeIndex возвращает позицию получателя в списке пользователя. Вызывается под блокировкой.
+func (ps *PayeesService) payeeIndex(userID, id string) int {
+	return slices.IndexFunc(ps.payees[userID], func(payee models.Payee) bool {
+		return payee.ID == id
+	})
+}
+
+// checkPayeeKeys проверяет, что название и псевдонимы не принадлежат другому получателю.
+// Вызывается под блокировкой.
+func (ps *PayeesService) checkPayeeKeys(userID string, payee models.Payee) error {
+	keys := payeeKeys(payee)
+	for _, other := range ps.payees[userID] {
+		if other.ID == payee.ID {
+			continue
+		}
+		for _, key := range payeeKeys(other) {
+			if slices.Contains(keys, key) {
+				return fmt.Errorf("%w: %s (%s)", errPayeeAliasTaken, key, other.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// normalizePayeeRequest проверяет получателя, убирает пустые и повторяющиеся псевдонимы
+func normalizePayeeRequest(payee models.Payee) (models.Payee, error) {
+	payee.Name = strings.TrimSpace(payee.Name)
+	if payee.Name == "" {
+		return models.Payee{}, errEmptyPayeeName
+	}
+	if normalizePayee(payee.Name) == "" {
+		return models.Payee{}, fmt.Errorf("%w: %s", errInvalidPayeeAlias, payee.Name)
+	}
+
+	keys := []string{normalizePayee(payee.Name)}
+	aliases := make([]string, 0, len(payee.Aliases))
+	for _, alias := range payee.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" {
+			continue
+		}
+
+		key := normalizePayee(alias)
+		if key == "" {
+			return models.Payee{}, fmt.Errorf("%w: %s", errInvalidPayeeAlias, alias)
+		}
+		if slices.Contains(keys, key) {
+			continue
+		}
+		keys = append(keys, key)
+		aliases = append(aliases, alias)
+	}
+
+	payee.Aliases = nil
+	if len(aliases) > 0 {
+		payee.Aliases = aliases
+	}
+
+	return payee, nil
+}
+
+// GetBackupData возвращает данные для бэкапа
+func (ps *PayeesService) GetBackupData() interface{} {
+	ps.mux.RLock()
+	defer ps.mux.RUnlock()
+
+	backupData := make(map[string][]models.Payee, len(ps.payees))
+	for userID, payees := range ps.payees {
+		backupData[userID] = backupPayees(payees)
+	}
+
+	return backupData
+}
+
+func backupPayees(payees []models.Payee) []models.Payee {
+	backupPayees := make([]models.Payee, len(payees))
+	for i, payee := range payees {
+		backupPayees[i] = copyPayee(payee)
+	}
+
+	return backupPayees
+}
+
+func copyPayee(payee models.Payee) models.Payee {
+	copied := payee
+	copied.Aliases = slices.Clone(payee.Aliases)
+
+	return copied
+}
+
+// ExportUserPayees возвращает получателей пользователя в формате бэкапа
+func (ps *PayeesService) ExportUserPayees(ctx context.Context) []models.Payee {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	ps.mux.RLock()
+	defer ps.mux.RUnlock()
+
+	return backupPayees(ps.payees[userID])
+}
+
+// ImportUserPayees загружает получателей пользователя из архива. Получатель с существующим ID
+// заменяется; получатели, псевдонимы которых уже заняты, пропускаются. При replace
+// существующие получатели удаляются.
+func (ps *PayeesService) ImportUserPayees(ctx context.Context, payees []models.Payee, replace bool) (int, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	normalized := make([]models.Payee, 0, len(payees))
+	for _, payee := range payees {
+		if payee.ID == "" {
+			payee.ID = uuid.New().String()
+		}
+		normalizedPayee, err := normalizePayeeRequest(payee)
+		if err != nil {
+			return 0, fmt.Errorf("%w: payee %s: %w", models.ErrBadRequest, payee.ID, err)
+		}
+		normalized = append(normalized, normalizedPayee)
+	}
+
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	if replace {
+		ps.payees[userID] = nil
+	}
+
+	imported := 0
+	for _, payee := range normalized {
+		if ps.checkPayeeKeys(userID, payee) != nil {
+			continue
+		}
+		if index := ps.payeeIndex(userID, payee.ID); index != -1 {
+			ps.payees[userID][index] = payee
+		} else {
+			ps.payees[userID] = append(ps.payees[userID], payee)
+		}
+		imported++
+	}
+
+	return imported, nil
+}
+
+// DeleteUserData удаляет получателей пользователя
+func (ps *PayeesService) DeleteUserData(userID string) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	delete(ps.payees, userID)
+}
+
+// GetBackupFileName возвращает имя файла для бэкапа
+func (ps *PayeesService) GetBackupFileName() string {
+	return "payees"
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errReceiptFieldMissing   = errors.New("receipt field is missing")
+	errInvalidReceiptField   = errors.New("invalid receipt field")
+	errUnknownReceiptKind    = errors.New("unknown receipt operation type, must be one of: 1, 2, 3, 4")
+	errInvalidReceiptPayload = errors.New("invalid receipt qr string")
+)
+
+// receiptTimeLayouts форматы времени чека: кассы пишут время с секундами или без них
+var receiptTimeLayouts = []string{"20060102T150405", "20060102T1504"}
+
+// Признаки расчета чека (параметр n): приход и возврат расхода - траты покупателя,
+// возврат прихода и расход - поступления
+const (
+	receiptIncome        = "1"
+	receiptIncomeReturn  = "2"
+	receiptOutcome       = "3"
+	receiptOutcomeReturn = "4"
+)
+
+// fiscalReceipt данные чека из QR-кода
+type fiscalReceipt struct {
+	receipt models.Receipt
+	date    time.Time
+	amount  float64
+	// refund деньги вернулись покупателю: возврат прихода или расход
+	refund bool
+}
+
+// parseReceiptQR разбирает строку из QR-кода кассового чека по формату ФНС.
+// Обращения к сервису ФНС нет: состав покупки из строки не получить.
+func parseReceiptQR(qr string) (fiscalReceipt, error) {
+	values, err := url.ParseQuery(strings.TrimSpace(qr))
+	if err != nil {
+		return fiscalReceipt{}, fmt.Errorf("%w: %w", errInvalidReceiptPayload, err)
+	}
+
+	field := func(name string) (string, error) {
+		value := strings.TrimSpace(values.Get(name))
+		if value == "" {
+			return "", fmt.Errorf("%w: %s", errReceiptFieldMissing, name)
+		}
+		return value, nil
+	}
+
+	var receipt fiscalReceipt
+
+	rawTime, err := field("t")
+	if err != nil {
+		return fiscalReceipt{}, err
+	}
+	for _, layout := range receiptTimeLayouts {
+		if receipt.date, err = time.Parse(layout, rawTime); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fiscalReceipt{}, fmt.Errorf("%w: t: %s", errInvalidReceiptField, rawTime)
+	}
+	// В транзакциях хранится только дата
+	receipt.date = time.Date(receipt.date.Year(), receipt.date.Month(), receipt.date.Day(), 0, 0, 0, 0, time.UTC)
+
+	rawAmount, err := field("s")
+	if err != nil {
+		return fiscalReceipt{}, err
+	}
+	if receipt.amount, err = strconv.ParseFloat(rawAmount, 64); err != nil || receipt.amount <= 0 {
+		return fiscalReceipt{}, fmt.Errorf("%w: s: %s", errInvalidReceiptField, rawAmount)
+	}
+
+	fiscalFields := []struct {
+		name   string
+		target *string
+	}{
+		{"fn", &receipt.receipt.FN},
+		{"i", &receipt.receipt.I},
+		{"fp", &receipt.receipt.FP},
+	}
+	for _, fiscalField := range fiscalFields {
+		value, err := field(fiscalField.name)
+		if err != nil {
+			return fiscalReceipt{}, err
+		}
+		if strings.IndexFunc(value, func(r rune) bool { return !unicode.IsDigit(r) }) != -1 {
+			return fiscalReceipt{}, fmt.Errorf("%w: %s: %s", errInvalidReceiptField, fiscalField.name, value)
+		}
+		*fiscalField.target = value
+	}
+
+	// Без признака расчета считаем чек обычной покупкой
+	switch kind := values.Get("n"); kind {
+	case "", receiptIncome, receiptOutcomeReturn:
+	case receiptIncomeReturn, receiptOutcome:
+		receipt.refund = true
+	default:
+		return fiscalReceipt{}, fmt.Errorf("%w: %s", errUnknownReceiptKind, kind)
+	}
+
+	return receipt, nil
+}
+
+// receiptKey ключ чека в индексе шарда
+func receiptKey(receipt models.Receipt) string {
+	return receipt.FN + "/" + receipt.I + "/" + receipt.FP
+}
+
+// CreateTransactionFromReceipt создает транзакцию по QR-коду чека. Повторное сканирование
+// того же чека (те же fn, i и fp) возвращает уже созданную транзакцию. Название заполняется
+// номером чека, категория - правилами пользователя, а если правило не подошло - категорией
+// по умолчанию; возвраты записываются в доходы.
+func (ts *TransactionsService) CreateTransactionFromReceipt(ctx context.Context, req models.ReceiptRequest) (*models.ReceiptResponse, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	receipt, err := parseReceiptQR(req.QR)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, err)
+	}
+
+	shard := ts.userShard(userID)
+
+	// Повторный скан не должен применять правила и проверять поля запроса заново
+	shard.mux.RLock()
+	existing, scanned := shard.receiptTransaction(receipt.receipt)
+	shard.mux.RUnlock()
+	if scanned {
+		return &models.ReceiptResponse{Created: false, Transaction: existing}, nil
+	}
+
+	createRequest := models.CreateTransactionRequest{
+		Amount:   receipt.amount,
+		Title:    req.Title,
+		Category: req.Category,
+		Date:     receipt.date.Format("2006-01-02"),
+		Tags:     req.Tags,
+		Note:     req.Note,
+	}
+	if createRequest.Title == "" {
+		createRequest.Title = "Чек №" + receipt.receipt.I
+		if receipt.refund {
+			createRequest.Title = "Возврат по чеку №" + receipt.receipt.I
+		}
+	}
+	if createRequest.Category == "" && receipt.refund {
+		createRequest.Category = models.IncomeCategory
+	}
+
+	transaction, err := ts.prepareTransaction(ctx, createRequest)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Category == "" {
+		transaction.Category = defaultImportCategory
+	}
+	transaction.Receipt = &receipt.receipt
+
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	// Чек могли отсканировать параллельно, пока готовилась транзакция
+	if existing, scanned := shard.receiptTransaction(receipt.receipt); scanned {
+		return &models.ReceiptResponse{Created: false, Transaction: existing}, nil
+	}
+
+	shard.put(transaction)
+
+	return &models.ReceiptResponse{Created: true, Transaction: transaction}, nil
+}
+
+// receiptTransaction возвращает транзакцию, созданную по чеку. Вызывается под блокировкой шарда.
+func (us *userShard) receiptTransaction(receipt models.Receipt) (models.Transaction, bool) {
+	transactionID, exists := us.receiptIndex[receiptKey(receipt)]
+	if !exists {
+		return models.Transaction{}, false
+	}
+
+	return us.transactions[transactionID], true
+}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	_ "embed"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"spendings-backend/internal/models"
+	"spendings-backend/pkg/pdf"
+)
+
+// reportFontData шрифт DejaVu Sans с кириллицей для PDF-отчетов (лицензия в fonts/LICENSE)
+//
+//go:embed fonts/DejaVuSans.ttf
+var reportFontData []byte
+
+const (
+	reportMargin      = 40.0
+	reportLineHeight  = 16.0
+	reportTopExpenses = 10
+	reportChartHeight = 180.0
+)
+
+var (
+	reportTextColor  = pdf.Color{R: 0.13, G: 0.13, B: 0.13}
+	reportMutedColor = pdf.Color{R: 0.45, G: 0.45, B: 0.45}
+	reportGridColor  = pdf.Color{R: 0.85, G: 0.85, B: 0.85}
+	reportAccent     = pdf.Color{R: 0.2, G: 0.45, B: 0.8}
+	reportIncome     = pdf.Color{R: 0.18, G: 0.6, B: 0.3}
+	reportExpense    = pdf.Color{R: 0.8, G: 0.25, B: 0.2}
+)
+
+var reportMonthNames = [...]string{
+	"январь", "февраль", "март", "апрель", "май", "июнь",
+	"июль", "август", "сентябрь", "октябрь", "ноябрь", "декабрь",
+}
+
+type StatisticsProvider interface {
+	GetStatistics(ctx context.Context, fromDate, toDate time.Time) (*models.StatisticsResponse, error)
+}
+
+// ReportService сервис формирования PDF-отчетов
+type ReportService struct {
+	statisticsService   StatisticsProvider
+	transactionsService TransactionsProvider
+	categoriesService   CategoriesProvider
+	font                *pdf.Font
+}
+
+// NewReportService создает новый сервис отчетов со встроенным шрифтом
+func NewReportService(statisticsService StatisticsProvider, transactionsService TransactionsProvider, categoriesService CategoriesProvider) (*ReportService, error) {
+	font, err := pdf.ParseTrueType(reportFontData)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse report font: %w", err)
+	}
+
+	return &ReportService{
+		statisticsService:   statisticsService,
+		transactionsService: transactionsService,
+		categoriesService:   categoriesService,
+		font:                font,
+	}, nil
+}
+
+// GetMonthlyReport формирует PDF-отчет за месяц: итоги, расходы по категориям,
+// крупнейшие расходы и график баланса по дням
+func (rs *ReportService) GetMonthlyReport(ctx context.Context, month time.Time) ([]byte, error) {
+	fromDate := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	toDate := fromDate.AddDate(0, 1, -1)
+
+	statistics, err := rs.statisticsService.GetStatistics(ctx, fromDate, toDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statistics: %w", err)
+	}
+
+	transactions, err := rs.transactionsService.GetAllTransactions(ctx, fromDate, toDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	income, err := rs.categoriesService.GetIncomeCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	// Статистика уже показывает категории под новыми названиями, транзакции - нет
+	names, err := rs.categoriesService.GetCategoryNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	title := fmt.Sprintf("Финансовый отчет за %s %d", reportMonthNames[fromDate.Month()-1], fromDate.Year())
+
+	report := newReportLayout(pdf.NewDocument(rs.font, title))
+
+	report.text(reportMargin, 20, reportTextColor, title)
+	report.y += 10
+	report.text(reportMargin, 10, reportMutedColor, fmt.Sprintf("Период: %s - %s. Сформирован %s.",
+		fromDate.Format("02.01.2006"), toDate.Format("02.01.2006"), time.Now().Format("02.01.2006 15:04")))
+	report.y += 10
+
+	report.totals(statistics.GeneralStatistics)
+	report.categories(statistics.CategoryBreakdown, statistics.GeneralStatistics.Expenses)
+	report.topExpenses(transactions, income, names)
+	report.balanceChart(statistics.BalanceChangesByDate, fromDate, toDate)
+
+	var buf bytes.Buffer
+	if _, err := report.document.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("can't write report: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// reportLayout размещает блоки отчета сверху вниз и переносит их на новую страницу
+type reportLayout struct {
+	document *pdf.Document
+	page     *pdf.Page
+	y        float64
+}
+
+func newReportLayout(document *pdf.Document) *reportLayout {
+	return &reportLayout{
+		document: document,
+		page:     document.AddPage(),
+		y:        reportMargin,
+	}
+}
+
+// ensureSpace начинает новую страницу, если на текущей не помещается блок высотой height
+func (rl *reportLayout) ensureSpace(height float64) {
+	if rl.y+height > pdf.PageHeight-reportMargin {
+		rl.page = rl.document.AddPage()
+		rl.y = reportMargin
+	}
+}
+
+// text выводит строку и сдвигает позицию на ее высоту
+func (rl *reportLayout) text(x, size float64, color pdf.Color, text string) {
+	rl.ensureSpace(size * 1.4)
+	rl.y += size
+	rl.page.Text(x, rl.y, size, color, text)
+	rl.y += size * 0.4
+}
+
+func (rl *reportLayout) heading(text string) {
+	rl.ensureSpace(60)
+	rl.y += 14
+	rl.text(reportMargin, 14, reportTextColor, text)
+	rl.page.Line(pdf.Point{X: reportMargin, Y: rl.y}, pdf.Point{X: pdf.PageWidth - reportMargin, Y: rl.y}, 0.5, reportGridColor)
+	rl.y += 6
+}
+
+// row выводит строку таблицы; последний столбец выравнивается по правому краю
+func (rl *reportLayout) row(columns []float64, values []string, color pdf.Color) {
+	const size = 10
+
+	rl.ensureSpace(reportLineHeight)
+	baseline := rl.y + size
+
+	right := pdf.PageWidth - reportMargin
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+
+		if i == len(values)-1 {
+			rl.page.Text(right-rl.document.TextWidth(value, size), baseline, size, color, value)
+			continue
+		}
+
+		limit := right - columns[i] - 90
+		if i+1 < len(columns) {
+			limit = columns[i+1] - columns[i] - 8
+		}
+		rl.page.Text(columns[i], baseline, size, color, rl.truncate(value, size, limit))
+	}
+
+	rl.y += reportLineHeight
+}
+
+// truncate обрезает текст по ширине с многоточием
+func (rl *reportLayout) truncate(text string, size, width float64) string {
+	if rl.document.TextWidth(text, size) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && rl.document.TextWidth(string(runes)+"…", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+
+	return string(runes) + "…"
+}
+
+func (rl *reportLayout) totals(general models.GeneralStatistics) {
+	rl.heading("Итоги месяца")
+
+	columns := []float64{reportMargin}
+	rl.row(columns, []string{"Доходы", reportAmount(general.Income)}, reportIncome)
+	rl.row(columns, []string{"Расходы", reportAmount(general.Expenses)}, reportExpense)
+	rl.row(columns, []string{"Баланс", reportAmount(general.Balance)}, reportTextColor)
+}
+
+func (rl *reportLayout) categories(categories []models.CategoryStatistics, totalExpenses float64) {
+	rl.heading("Расходы по категориям")
+
+	if len(categories) == 0 {
+		rl.text(reportMargin, 10, reportMutedColor, "Расходов за месяц нет")
+		return
+	}
+
+	for _, category := range categories {
+		rl.categoryRow(category, totalExpenses, 0)
+	}
+}
+
+// categoryRow выводит категорию с полосой доли в расходах и ее подкатегории с отступом
+func (rl *reportLayout) categoryRow(category models.CategoryStatistics, totalExpenses float64, depth int) {
+	const barX, barWidth, indent = 220.0, 180.0, 12.0
+	// Второй столбец занят полосой доли категории
+	columns := []float64{reportMargin + indent*float64(depth), barX, barX + barWidth + 10}
+
+	share := 0.0
+	if totalExpenses > 0 {
+		share = category.Amount / totalExpenses
+	}
+
+	color := reportTextColor
+	if depth > 0 {
+		color = reportMutedColor
+	}
+
+	rl.ensureSpace(reportLineHeight)
+	rl.page.Rect(barX, rl.y+2, barWidth, 9, reportGridColor)
+	rl.page.Rect(barX, rl.y+2, barWidth*share, 9, reportAccent)
+
+	rl.row(columns, []string{
+		category.Category,
+		"",
+		strings.Replace(fmt.Sprintf("%.1f%%", share*100), ".", ",", 1),
+		reportAmount(category.Amount),
+	}, color)
+
+	for _, child := range category.Children {
+		rl.categoryRow(child, totalExpenses, depth+1)
+	}
+}
+
+func (rl *reportLayout) topExpenses(transactions []models.Transaction, income incomeCategories, names categoryNames) {
+	rl.heading("Крупнейшие расходы")
+
+	expenses := make([]models.Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		if !income.isIncome(transaction.Category) {
+			expenses = append(expenses, transaction)
+		}
+	}
+
+	if len(expenses) == 0 {
+		rl.text(reportMargin, 10, reportMutedColor, "Расходов за месяц нет")
+		return
+	}
+
+	slices.SortFunc(expenses, func(a, b models.Transaction) int {
+		if c := cmp.Compare(b.Amount, a.Amount); c != 0 {
+			return c
+		}
+		return a.Date.Compare(b.Date)
+	})
+
+	columns := []float64{reportMargin, reportMargin + 70, reportMargin + 290}
+	rl.row(columns, []string{"Дата", "Название", "Категория", "Сумма"}, reportMutedColor)
+
+	for _, transaction := range expenses[:min(reportTopExpenses, len(expenses))] {
+		rl.row(columns, []string{
+			transaction.Date.Format("02.01.2006"),
+			transaction.Title,
+			names.display(transaction.Category),
+			reportAmount(transaction.Amount),
+		}, reportTextColor)
+	}
+}
+
+// balanceChart рисует накопленный баланс месяца по дням
+func (rl *reportLayout) balanceChart(balanceChanges map[string]float64, fromDate, toDate time.Time) {
+	rl.heading("Баланс по дням")
+	rl.ensureSpace(reportChartHeight + 30)
+
+	days := int(toDate.Sub(fromDate).Hours()/24) + 1
+	balances := make([]float64, days)
+
+	balance, low, high := 0.0, 0.0, 0.0
+	for day := range days {
+		balance += balanceChanges[fromDate.AddDate(0, 0, day).Format("2006-01-02")]
+		balances[day] = balance
+		low, high = math.Min(low, balance), math.Max(high, balance)
+	}
+	if high == low {
+		high = low + 1
+	}
+
+	const labelWidth = 80.0
+	left, top := reportMargin+labelWidth, rl.y+5
+	width, height := pdf.PageWidth-reportMargin-left, reportChartHeight
+
+	x := func(day int) float64 {
+		return left + width*float64(day)/float64(max(days-1, 1))
+	}
+	y := func(value float64) float64 {
+		return top + height*(high-value)/(high-low)
+	}
+
+	// Рамка, нулевая линия и подписи осей
+	rl.page.Polyline([]pdf.Point{{X: left, Y: top}, {X: left, Y: top + height}, {X: left + width, Y: top + height}}, 0.5, reportMutedColor)
+	rl.page.Line(pdf.Point{X: left, Y: y(0)}, pdf.Point{X: left + width, Y: y(0)}, 0.5, reportGridColor)
+
+	for _, value := range slices.Compact([]float64{high, 0, low}) {
+		label := reportAmount(value)
+		rl.page.Text(left-6-rl.document.TextWidth(label, 8), y(value)+3, 8, reportMutedColor, label)
+	}
+
+	for day := 0; day < days; day += 5 {
+		label := fromDate.AddDate(0, 0, day).Format("02.01")
+		rl.page.Text(x(day)-rl.document.TextWidth(label, 8)/2, top+height+12, 8, reportMutedColor, label)
+	}
+
+	points := make([]pdf.Point, days)
+	for day, value := range balances {
+		points[day] = pdf.Point{X: x(day), Y: y(value)}
+	}
+	rl.page.Polyline(points, 1.5, reportAccent)
+
+	rl.y = top + height + 20
+}
+
+// reportAmount форматирует сумму в рублях с разделителями разрядов
+func reportAmount(amount float64) string {
+	return formatAmount(amount, exportLocales[models.LocaleRU]) + " ₽"
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"spendings-backend/internal/models"
+	"spendings-backend/pkg/pdf"
+)
+
+var (
+	pdfStartXref  = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	pdfXrefHeader = regexp.MustCompile(`^xref\n0 (\d+)\n`)
+	pdfLength     = regexp.MustCompile(`/Length (\d+)`)
+	pdfReference  = regexp.MustCompile(`(\d+) 0 R`)
+	pdfText       = regexp.MustCompile(`BT /F1 \S+ Tf \S+ \S+ \S+ rg \S+ (\S+) Td <([0-9A-F]*)> Tj ET`)
+	pdfBfchar     = regexp.MustCompile(`<([0-9A-F]{4})> <([0-9A-F]+)>`)
+)
+
+// parsedPDF объекты PDF-файла, найденные по таблице xref; потоки хранятся распакованными
+type parsedPDF struct {
+	objects map[int]string
+	streams map[int][]byte
+}
+
+// parsePDF разбирает файл, записанный pdf.Document, по смещениям объектов из таблицы xref
+func parsePDF(t *testing.T, data []byte) parsedPDF {
+	t.Helper()
+
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-1.7\n")), "pdf header")
+
+	match := pdfStartXref.FindSubmatch(data)
+	require.NotNil(t, match, "startxref")
+	xref, err := strconv.Atoi(string(match[1]))
+	require.NoError(t, err)
+	require.Less(t, xref, len(data))
+
+	header := pdfXrefHeader.FindSubmatch(data[xref:])
+	require.NotNil(t, header, "xref header")
+	size, err := strconv.Atoi(string(header[1]))
+	require.NoError(t, err)
+
+	parsed := parsedPDF{objects: make(map[int]string), streams: make(map[int][]byte)}
+
+	// Записи xref по 20 байт, первая описывает свободный объект 0
+	entries := data[xref+len(header[0])+20:]
+	for number := 1; number < size; number++ {
+		entry := string(entries[(number-1)*20 : number*20])
+		require.True(t, strings.HasSuffix(entry, " 00000 n \n"), "xref entry %d", number)
+		offset, err := strconv.Atoi(entry[:10])
+		require.NoError(t, err)
+
+		prefix := fmt.Sprintf("%d 0 obj\n", number)
+		require.True(t, bytes.HasPrefix(data[offset:], []byte(prefix)), "object %d at offset %d", number, offset)
+
+		dictionary, rest, _ := bytes.Cut(data[offset+len(prefix):], []byte("\n"))
+		parsed.objects[number] = string(dictionary)
+
+		if !bytes.HasPrefix(rest, []byte("stream\n")) {
+			require.True(t, bytes.HasPrefix(rest, []byte("endobj\n")), "object %d end", number)
+			continue
+		}
+
+		length := pdfLength.FindStringSubmatch(string(dictionary))
+		require.NotNil(t, length, "stream %d length", number)
+		n, err := strconv.Atoi(length[1])
+		require.NoError(t, err)
+
+		compressed := rest[len("stream\n"):]
+		require.True(t, bytes.HasPrefix(compressed[n:], []byte("\nendstream\nendobj\n")), "stream %d end", number)
+
+		reader, err := zlib.NewReader(bytes.NewReader(compressed[:n]))
+		require.NoError(t, err, "stream %d", number)
+		parsed.streams[number], err = io.ReadAll(reader)
+		require.NoError(t, err, "stream %d", number)
+	}
+
+	return parsed
+}
+
+// reference возвращает номер объекта, на который ссылается ключ key словаря объекта number
+func (p parsedPDF) reference(t *testing.T, number int, key string) int {
+	t.Helper()
+
+	_, after, found := strings.Cut(p.objects[number], key+" ")
+	require.True(t, found, "object %d has no %s", number, key)
+	match := pdfReference.FindStringSubmatch(after)
+	require.NotNil(t, match, "object %d %s", number, key)
+
+	reference, err := strconv.Atoi(match[1])
+	require.NoError(t, err)
+
+	return reference
+}
+
+// toUnicode возвращает таблицу ToUnicode шрифта страниц: глиф в hex -> текст
+func (p parsedPDF) toUnicode(t *testing.T, font int) map[string]string {
+	t.Helper()
+
+	table := make(map[string]string)
+	for _, match := range pdfBfchar.FindAllStringSubmatch(string(p.streams[p.reference(t, font, "/ToUnicode")]), -1) {
+		units, err := hex.DecodeString(match[2])
+		require.NoError(t, err)
+
+		decoded := make([]uint16, len(units)/2)
+		for i := range decoded {
+			decoded[i] = binary.BigEndian.Uint16(units[i*2:])
+		}
+		table[match[1]] = string(utf16.Decode(decoded))
+	}
+
+	return table
+}
+
+// pdfTextLine строка текста страницы; y отсчитывается от нижнего края, как в PDF
+type pdfTextLine struct {
+	y    float64
+	text string
+}
+
+// pages возвращает строки текста каждой страницы в порядке дерева страниц
+func (p parsedPDF) pages(t *testing.T) [][]pdfTextLine {
+	t.Helper()
+
+	tree := p.reference(t, 1, "/Pages")
+	_, kids, found := strings.Cut(p.objects[tree], "/Kids [")
+	require.True(t, found, "pages tree has no kids")
+	kids, _, _ = strings.Cut(kids, "]")
+
+	var pages [][]pdfTextLine
+	for _, kid := range pdfReference.FindAllStringSubmatch(kids, -1) {
+		page, err := strconv.Atoi(kid[1])
+		require.NoError(t, err)
+		require.Contains(t, p.objects[page], "/Type /Page ")
+		toUnicode := p.toUnicode(t, p.reference(t, page, "/F1"))
+
+		var lines []pdfTextLine
+		for _, operator := range pdfText.FindAllStringSubmatch(string(p.streams[p.reference(t, page, "/Contents")]), -1) {
+			y, err := strconv.ParseFloat(operator[1], 64)
+			require.NoError(t, err)
+
+			var text strings.Builder
+			for glyphs := operator[2]; glyphs != ""; glyphs = glyphs[4:] {
+				decoded, exists := toUnicode[glyphs[:4]]
+				require.True(t, exists, "glyph %s is missing in ToUnicode", glyphs[:4])
+				text.WriteString(decoded)
+			}
+			lines = append(lines, pdfTextLine{y: y, text: text.String()})
+		}
+		pages = append(pages, lines)
+	}
+
+	return pages
+}
+
+// pageTexts возвращает только тексты строк страницы
+func pageTexts(lines []pdfTextLine) []string {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.text
+	}
+
+	return texts
+}
+
+// newReportService собирает сервис отчетов на настоящих сервисах транзакций и статистики
+func newReportService(t *testing.T, userCategories []models.Category, transactions map[string]models.Transaction) *ReportService {
+	categories := NewCategoriesService(map[string][]models.Category{"report": userCategories}, models.GetDefaultBaseCategories())
+	ts := NewTransactionsService(map[string]map[string]models.Transaction{"report": transactions}, categories, NewRulesService(nil), nil, time.Hour)
+
+	rs, err := NewReportService(NewStatisticsService(ts, categories, NewPayeesService(nil)), ts, categories)
+	require.NoError(t, err)
+
+	return rs
+}
+
+func TestMonthlyReportSinglePage(t *testing.T) {
+	rs := newReportService(t, nil, map[string]models.Transaction{
+		"1": {ID: "1", Title: "Зарплата", Category: models.IncomeCategory, Amount: 100000, Date: time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC)},
+		"2": {ID: "2", Title: "Пятерочка", Category: "Еда", Amount: 1234.5, Date: time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC)},
+		// Транзакция другого месяца в отчет не попадает
+		"3": {ID: "3", Title: "Кинотеатр", Category: "Развлечения", Amount: 700, Date: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)},
+	})
+
+	data, err := rs.GetMonthlyReport(userContext("report"), time.Date(2026, time.March, 20, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	parsed := parsePDF(t, data)
+	assert.Contains(t, parsed.objects[2], "/Count 1")
+
+	pages := parsed.pages(t)
+	require.Len(t, pages, 1)
+
+	texts := pageTexts(pages[0])
+	assert.Equal(t, "Финансовый отчет за март 2026", texts[0])
+	assert.Subset(t, texts, []string{
+		"Итоги месяца", "Доходы", "100 000,00 ₽", "Расходы", "1 234,50 ₽", "Баланс", "98 765,50 ₽",
+		"Расходы по категориям", "Еда", "100,0%",
+		"Крупнейшие расходы", "10.03.2026", "Пятерочка",
+		"Баланс по дням",
+	})
+	assert.NotContains(t, texts, "Кинотеатр")
+
+	// Заголовок документа в сведениях записан в UTF-16BE
+	assert.Contains(t, parsed.objects[8], "/Title <FEFF"+strings.ToUpper(hex.EncodeToString(utf16BE("Финансовый отчет за март 2026")))+">")
+}
+
+func TestMonthlyReportIncomeCategories(t *testing.T) {
+	// Пользовательская категория с типом доходов не попадает в расходы отчета
+	rs := newReportService(t, []models.Category{{Name: "Фриланс", Type: models.CategoryTypeIncome}}, map[string]models.Transaction{
+		"1": {ID: "1", Title: "Зарплата", Category: models.IncomeCategory, Amount: 50000, Date: time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC)},
+		"2": {ID: "2", Title: "Заказ", Category: "Фриланс", Amount: 80000, Date: time.Date(2026, time.March, 12, 0, 0, 0, 0, time.UTC)},
+		"3": {ID: "3", Title: "Пятерочка", Category: "Еда", Amount: 1000, Date: time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC)},
+	})
+
+	data, err := rs.GetMonthlyReport(userContext("report"), time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	pages := parsePDF(t, data).pages(t)
+	require.Len(t, pages, 1)
+
+	texts := pageTexts(pages[0])
+	assert.Subset(t, texts, []string{"130 000,00 ₽", "Пятерочка", "Еда"})
+	assert.NotContains(t, texts, "Заказ")
+	assert.NotContains(t, texts, "Фриланс")
+}
+
+func TestMonthlyReportPageBreaks(t *testing.T) {
+	// 70 категорий по строке на каждую не помещаются на первую страницу вместе с итогами
+	const categoriesCount = 70
+
+	transactions := map[string]models.Transaction{
+		"income": {ID: "income", Title: "Зарплата", Category: models.IncomeCategory, Amount: 500000, Date: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for i := range categoriesCount {
+		id := fmt.Sprintf("%02d", i)
+		transactions[id] = models.Transaction{
+			ID:       id,
+			Title:    "Покупка " + id,
+			Category: "Категория " + id,
+			// Суммы различаются, поэтому порядок категорий в отчете однозначен
+			Amount: float64(1000 + i),
+			Date:   time.Date(2026, time.March, 1+i%31, 0, 0, 0, 0, time.UTC),
+		}
+	}
+
+	data, err := newReportService(t, nil, transactions).GetMonthlyReport(userContext("report"), time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	parsed := parsePDF(t, data)
+	pages := parsed.pages(t)
+
+	// Таблица категорий и таблица крупнейших расходов переходят на следующую страницу,
+	// график не помещается под таблицей и начинается на третьей
+	require.Len(t, pages, 3)
+	assert.Contains(t, parsed.objects[2], "/Count 3")
+
+	page := func(text string) int {
+		for i, lines := range pages {
+			for _, line := range lines {
+				if line.text == text {
+					return i + 1
+				}
+			}
+		}
+		return 0
+	}
+
+	assert.Equal(t, 1, page("Финансовый отчет за март 2026"))
+	assert.Equal(t, 1, page("Итоги месяца"))
+	assert.Equal(t, 1, page("Расходы по категориям"))
+	assert.Equal(t, 1, page("Категория 69"), "the largest category opens the table")
+	assert.Equal(t, 2, page("Категория 00"), "the smallest category closes the table")
+	assert.Equal(t, 2, page("Крупнейшие расходы"))
+	assert.Equal(t, 2, page("Покупка 61"))
+	assert.Equal(t, 3, page("Покупка 60"), "the last top expense moves to the next page")
+	assert.Equal(t, 3, page("Баланс по дням"))
+
+	// Таблица категорий выводит каждую категорию один раз по убыванию суммы через границу страниц,
+	// остальные строки с категориями - столбец таблицы крупнейших расходов
+	var categories []string
+	for _, lines := range pages {
+		for _, line := range lines {
+			if strings.HasPrefix(line.text, "Категория ") {
+				categories = append(categories, line.text)
+			}
+		}
+	}
+	require.Len(t, categories, categoriesCount+reportTopExpenses)
+	for i, category := range categories[:categoriesCount] {
+		assert.Equal(t, fmt.Sprintf("Категория %02d", categoriesCount-1-i), category)
+	}
+
+	// Текст каждой страницы не заходит на поля
+	for i, lines := range pages {
+		for _, line := range lines {
+			assert.GreaterOrEqual(t, line.y, reportMargin, "page %d: %q", i+1, line.text)
+			assert.LessOrEqual(t, line.y, pdf.PageHeight-reportMargin, "page %d: %q", i+1, line.text)
+		}
+	}
+}
+
+func TestMonthlyReportCyrillicFont(t *testing.T) {
+	rs := newReportService(t, nil, map[string]models.Transaction{
+		"1": {ID: "1", Title: "Съешь же ещё этих мягких французских булок", Category: "Еда", Amount: 350, Date: time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC)},
+	})
+
+	data, err := rs.GetMonthlyReport(userContext("report"), time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	parsed := parsePDF(t, data)
+	page := parsed.reference(t, parsed.reference(t, 1, "/Pages"), "/Kids")
+
+	// Составной шрифт с кодировкой Identity-H: коды в строках текста - номера глифов
+	font := parsed.reference(t, page, "/F1")
+	assert.Contains(t, parsed.objects[font], "/Subtype /Type0")
+	assert.Contains(t, parsed.objects[font], "/Encoding /Identity-H")
+
+	descendant := parsed.reference(t, font, "/DescendantFonts")
+	assert.Contains(t, parsed.objects[descendant], "/Subtype /CIDFontType2")
+	assert.Contains(t, parsed.objects[descendant], "/CIDToGIDMap /Identity")
+
+	// Подмножество шрифта встроено в документ и заметно меньше исходного файла
+	fontFile := parsed.reference(t, parsed.reference(t, descendant, "/FontDescriptor"), "/FontFile2")
+	embedded := parsed.streams[fontFile]
+	assert.Contains(t, parsed.objects[fontFile], fmt.Sprintf("/Length1 %d", len(embedded)))
+	assert.Less(t, len(embedded), len(reportFontData)/4)
+
+	// У каждого глифа кириллицы, выведенного в отчете, во встроенном шрифте есть контуры
+	outlines := glyphOutlines(t, embedded)
+	cyrillic := make(map[rune]struct{})
+	for code, text := range parsed.toUnicode(t, font) {
+		glyph, err := strconv.ParseUint(code, 16, 16)
+		require.NoError(t, err)
+
+		for _, r := range text {
+			if !unicode.Is(unicode.Cyrillic, r) {
+				continue
+			}
+			cyrillic[r] = struct{}{}
+			if assert.Less(t, int(glyph), len(outlines), "glyph for %q", r) {
+				assert.True(t, outlines[glyph], "glyph for %q has no outline", r)
+			}
+		}
+	}
+
+	for _, r := range "Финансовый отчет за март Съешь же ещё" {
+		if unicode.IsLetter(r) {
+			assert.Contains(t, cyrillic, r)
+		}
+	}
+
+	// Название транзакции обрезается по ширине столбца, но читается через ToUnicode
+	texts := pageTexts(parsed.pages(t)[0])
+	assert.Condition(t, func() bool {
+		for _, text := range texts {
+			if strings.HasPrefix(text, "Съешь же ещё") {
+				return true
+			}
+		}
+		return false
+	}, "transaction title in %v", texts)
+}
+
+// utf16BE кодирует строку в UTF-16BE без метки порядка байтов
+func utf16BE(text string) []byte {
+	var encoded []byte
+	for _, unit := range utf16.Encode([]rune(text)) {
+		encoded = binary.BigEndian.AppendUint16(encoded, unit)
+	}
+
+	return encoded
+}
+
+// glyphOutlines возвращает для каждого глифа встроенного TrueType-шрифта, есть ли у него контуры
+func glyphOutlines(t *testing.T, font []byte) []bool {
+	t.Helper()
+
+	require.Greater(t, len(font), 12)
+	require.Equal(t, uint32(0x00010000), binary.BigEndian.Uint32(font), "sfnt version")
+
+	tables := make(map[string][]byte)
+	for i := range int(binary.BigEndian.Uint16(font[4:])) {
+		record := font[12+i*16:]
+		offset, length := binary.BigEndian.Uint32(record[8:]), binary.BigEndian.Uint32(record[12:])
+		require.LessOrEqual(t, int(offset+length), len(font), "table %s", record[:4])
+		tables[string(record[:4])] = font[offset : offset+length]
+	}
+
+	require.Contains(t, tables, "head")
+	require.Contains(t, tables, "loca")
+	require.Contains(t, tables, "glyf")
+	require.Equal(t, uint16(1), binary.BigEndian.Uint16(tables["head"][50:]), "loca uses 32-bit offsets")
+
+	loca := tables["loca"]
+	outlines := make([]bool, len(loca)/4-1)
+	for glyph := range outlines {
+		start, end := binary.BigEndian.Uint32(loca[glyph*4:]), binary.BigEndian.Uint32(loca[glyph*4+4:])
+		require.LessOrEqual(t, int(end), len(tables["glyf"]), "glyph %d", glyph)
+		outlines[glyph] = start < end
+	}
+
+	return outlines
+}
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errRuleWithoutCondition = errors.New("rule must have at least one condition")
+	errRuleWithoutAction    = errors.New("rule must set category, tags or rename")
+	errInvalidRuleRegex     = errors.New("invalid title regex")
+	errInvalidRuleAmount    = errors.New("minAmount must not be greater than maxAmount")
+)
+
+// ruleWeekdays короткие названия дней недели, в которых хранятся условия правил
+var ruleWeekdays = map[time.Weekday]string{
+	time.Monday:    "mon",
+	time.Tuesday:   "tue",
+	time.Wednesday: "wed",
+	time.Thursday:  "thu",
+	time.Friday:    "fri",
+	time.Saturday:  "sat",
+	time.Sunday:    "sun",
+}
+
+// RulesMatcher находит правило пользователя для новой транзакции
+type RulesMatcher interface {
+	MatchRule(ctx context.Context, title string, amount float64, date time.Time) (models.Rule, bool)
+}
+
+// compiledRule правило с разобранными условиями
+type compiledRule struct {
+	rule       models.Rule
+	titleRegex *regexp.Regexp
+	weekdays   map[time.Weekday]struct{}
+}
+
+// RulesService хранит правила автоматической категоризации пользователей
+type RulesService struct {
+	rules map[string][]compiledRule // userID -> правила в порядке применения
+	mux   sync.RWMutex
+}
+
+func NewRulesService(initialData map[string][]models.Rule) *RulesService {
+	rs := &RulesService{
+		rules: make(map[string][]compiledRule, len(initialData)),
+	}
+
+	for userID, rules := range initialData {
+		for _, rule := range rules {
+			// Сохраненные правила уже проверены при создании, поврежденные пропускаем
+			if compiled, err := compileRule(rule); err == nil {
+				rs.rules[userID] = append(rs.rules[userID], compiled)
+			}
+		}
+	}
+
+	return rs
+}
+
+// GetRules возвращает правила пользователя в порядке применения
+func (rs *RulesService) GetRules(ctx context.Context) ([]models.Rule, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	rs.mux.RLock()
+	defer rs.mux.RUnlock()
+
+	return backupRules(rs.rules[userID]), nil
+}
+
+// CreateRule добавляет правило в конец списка пользователя
+func (rs *RulesService) CreateRule(ctx context.Context, rule models.Rule) (*models.Rule, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	rule.ID = uuid.New().String()
+	compiled, err := compileRule(rule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, err)
+	}
+
+	rs.mux.Lock()
+	defer rs.mux.Unlock()
+
+	rs.rules[userID] = append(rs.rules[userID], compiled)
+
+	created := copyRule(compiled.rule)
+
+	return &created, nil
+}
+
+// UpdateRule заменяет правило, сохраняя его место в списке
+func (rs *RulesService) UpdateRule(ctx context.Context, id string, rule models.Rule) (*models.Rule, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	rule.ID = id
+	compiled, err := compileRule(rule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, err)
+	}
+
+	rs.mux.Lock()
+	defer rs.mux.Unlock()
+
+	index := rs.ruleIndex(userID, id)
+	if index == -1 {
+		return nil, fmt.Errorf("%w: rule %s not found", models.ErrNotFound, id)
+	}
+	rs.rules[userID][index] = compiled
+
+	updated := copyRule(compiled.rule)
+
+	return &updated, nil
+}
+
+// DeleteRule удаляет правило пользователя
+func (rs *RulesService) DeleteRule(ctx context.Context, id string) error {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	rs.mux.Lock()
+	defer rs.mux.Unlock()
+
+	index := rs.ruleIndex(userID, id)
+	if index == -1 {
+		return fmt.Errorf("%w: rule %s not found", models.ErrNotFound, id)
+	}
+	rs.rules[userID] = slices.Delete(rs.rules[userID], index, index+1)
+
+	return nil
+}
+
+// ruleIndex возвращает позицию правила в списке пользователя. Вызывается под блокировкой.
+func (rs *RulesService) ruleIndex(userID, id string) int {
+	return slices.IndexFunc(rs.rules[userID], func(compiled compiledRule) bool {
+		return compiled.rule.ID == id
+	})
+}
+
+// MatchRule возвращает первое правило пользователя, условиям которого подходит транзакция
+func (rs *RulesService) MatchRule(ctx context.Context, title string, amount float64, date time.Time) (models.Rule, bool) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	rs.mux.RLock()
+	defer rs.mux.RUnlock()
+
+	for _, compiled := range rs.rules[userID] {
+		if compiled.matches(title, amount, date) {
+			return copyRule(compiled.rule), true
+		}
+	}
+
+	return models.Rule{}, false
+}
+
+func (cr compiledRule) matches(title string, amount float64, date time.Time) bool {
+	rule := cr.rule
+
+	if rule.TitleContains != "" && !strings.Contains(strings.ToLower(title), strings.ToLower(rule.TitleContains)) {
+		return false
+	}
+	if cr.titleRegex != nil && !cr.titleRegex.MatchString(title) {
+		return false
+	}
+	if rule.MinAmount != nil && amount < *rule.MinAmount {
+		return false
+	}
+	if rule.MaxAmount != nil && amount > *rule.MaxAmount {
+		return false
+	}
+	if len(cr.weekdays) > 0 {
+		if _, exists := cr.weekdays[date.Weekday()]; date.IsZero() || !exists {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compileRule проверяет правило и приводит условия и теги к единому виду
+func compileRule(rule models.Rule) (compiledRule, error) {
+	rule.TitleContains = strings.TrimSpace(rule.TitleContains)
+	rule.Category = strings.TrimSpace(rule.Category)
+	rule.Rename = strings.TrimSpace(rule.Rename)
+
+	compiled := compiledRule{weekdays: make(map[time.Weekday]struct{})}
+
+	if rule.TitleRegex != "" {
+		titleRegex, err := regexp.Compile(rule.TitleRegex)
+		if err != nil {
+			return compiledRule{}, fmt.Errorf("%w: %w", errInvalidRuleRegex, err)
+		}
+		compiled.titleRegex = titleRegex
+	}
+
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return compiledRule{}, errInvalidRuleAmount
+	}
+
+	weekdays := make([]string, 0, len(rule.Weekdays))
+	for _, day := range rule.Weekdays {
+		weekday, err := convertToWeekDay(strings.TrimSpace(day))
+		if err != nil {
+			return compiledRule{}, fmt.Errorf("%w, must be one of: mon, tue, wed, thu, fri, sat, sun", err)
+		}
+		if _, exists := compiled.weekdays[weekday]; !exists {
+			compiled.weekdays[weekday] = struct{}{}
+			weekdays = append(weekdays, ruleWeekdays[weekday])
+		}
+	}
+	rule.Weekdays = nil
+	if len(weekdays) > 0 {
+		rule.Weekdays = weekdays
+	}
+
+	tags, err := normalizeTags(rule.Tags)
+	if err != nil {
+		return compiledRule{}, fmt.Errorf("invalid tags: %w", err)
+	}
+	rule.Tags = tags
+
+	if rule.TitleContains == "" && rule.TitleRegex == "" && rule.MinAmount == nil && rule.MaxAmount == nil && len(rule.Weekdays) == 0 {
+		return compiledRule{}, errRuleWithoutCondition
+	}
+	if rule.Category == "" && rule.Rename == "" && len(rule.Tags) == 0 {
+		return compiledRule{}, errRuleWithoutAction
+	}
+
+	compiled.rule = rule
+
+	return compiled, nil
+}
+
+// applyRule применяет к запросу первое подходящее правило пользователя
+func applyRule(ctx context.Context, rules RulesMatcher, req *models.CreateTransactionRequest) bool {
+	// Дата проверяется при создании транзакции, здесь нужен только день недели
+	date, _ := time.Parse("2006-01-02", req.Date)
+
+	rule, matched := rules.MatchRule(ctx, req.Title, req.Amount, date)
+	if !matched {
+		return false
+	}
+
+	if rule.Category != "" {
+		req.Category = rule.Category
+	}
+	if rule.Rename != "" {
+		req.Title = rule.Rename
+	}
+	req.Tags = slices.Concat(req.Tags, rule.Tags)
+
+	return true
+}
+
+// ApplyRules применяет правила пользователя к сохраненным транзакциям. Первое подходящее
+// правило задает категорию, название и добавляет теги. Разбитые по категориям транзакции
+// и доходы, в том числе возвраты по чекам, пропускаются. В режиме dryRun возвращает изменения, не сохраняя их.
+func (ts *TransactionsService) ApplyRules(ctx context.Context, dryRun bool) (*models.ApplyRulesResult, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	income, err := ts.categories.GetIncomeCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	shard := ts.userShard(userID)
+
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	result := &models.ApplyRulesResult{
+		DryRun:  dryRun,
+		Changes: make([]models.RuleChange, 0),
+	}
+
+	// Новые транзакции первыми, как в списке транзакций
+	entries := shard.dateIndex.rangeOf(time.Time{}, time.Time{})
+	for i := len(entries) - 1; i >= 0; i-- {
+		transaction := shard.transactions[entries[i].id]
+		// Правила категоризируют расходы: доход под условие по сумме или дню недели попасть не должен
+		if len(transaction.Splits) > 0 || incomeCategories(income).isIncome(transaction.Category) {
+			continue
+		}
+
+		rule, matched := ts.rules.MatchRule(ctx, transaction.Title, transaction.Amount, transaction.Date)
+		if !matched {
+			continue
+		}
+
+		updated := transaction
+		if rule.Category != "" {
+			updated.Category = ts.categories.ResolveCategory(ctx, rule.Category)
+		}
+		if rule.Rename != "" {
+			updated.Title = rule.Rename
+		}
+		tags, err := normalizeTags(slices.Concat(transaction.Tags, rule.Tags))
+		if err != nil {
+			// Правило добавило бы больше тегов, чем допускается; теги оставляем как есть
+			tags = transaction.Tags
+		}
+		updated.Tags = tags
+
+		if updated.Category == transaction.Category && updated.Title == transaction.Title && slices.Equal(updated.Tags, transaction.Tags) {
+			continue
+		}
+
+		result.Changes = append(result.Changes, models.RuleChange{
+			TransactionID: transaction.ID,
+			RuleID:        rule.ID,
+			Title:         transaction.Title,
+			NewTitle:      updated.Title,
+			Category:      transaction.Category,
+			NewCategory:   updated.Category,
+			Tags:          transaction.Tags,
+			NewTags:       updated.Tags,
+		})
+
+		if !dryRun {
+			shard.put(updated)
+		}
+	}
+
+	result.Changed = len(result.Changes)
+
+	return result, nil
+}
+
+// GetBackupData возвращает данные для бэкапа
+func (rs *RulesService) GetBackupData() interface{} {
+	rs.mux.RLock()
+	defer rs.mux.RUnlock()
+
+	backupData := make(map[string][]models.Rule, len(rs.rules))
+	for userID, rules := range rs.rules {
+		backupData[userID] = backupRules(rules)
+	}
+
+	return backupData
+}
+
+func backupRules(rules []compiledRule) []models.Rule {
+	backupRules := make([]models.Rule, len(rules))
+	for i, compiled := range rules {
+		backupRules[i] = copyRule(compiled.rule)
+	}
+
+	return backupRules
+}
+
+// copyRule копирует правило вместе со срезами и указателями
+func copyRule(rule models.Rule) models.Rule {
+	copied := rule
+	copied.Weekdays = slices.Clone(rule.Weekdays)
+	copied.Tags = slices.Clone(rule.Tags)
+	if rule.MinAmount != nil {
+		minAmount := *rule.MinAmount
+		copied.MinAmount = &minAmount
+	}
+	if rule.MaxAmount != nil {
+		maxAmount := *rule.MaxAmount
+		copied.MaxAmount = &maxAmount
+	}
+
+	return copied
+}
+
+// ExportUserRules возвращает правила пользователя в формате бэкапа
+func (rs *RulesService) ExportUserRules(ctx context.Context) []models.Rule {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	rs.mux.RLock()
+	defer rs.mux.RUnlock()
+
+	return backupRules(rs.rules[userID])
+}
+
+// ImportUserRules загружает правила пользователя из архива. Правило с существующим ID
+// заменяется, остальные добавляются в конец; при replace существующие правила удаляются.
+func (rs *RulesService) ImportUserRules(ctx context.Context, rules []models.Rule, replace bool) (int, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	compiledRules := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.ID == "" {
+			rule.ID = uuid.New().String()
+		}
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return 0, fmt.Errorf("%w: rule %s: %w", models.ErrBadRequest, rule.ID, err)
+		}
+		compiledRules = append(compiledRules, compiled)
+	}
+
+	rs.mux.Lock()
+	defer rs.mux.Unlock()
+
+	if replace {
+		rs.rules[userID] = nil
+	}
+
+	for _, compiled := range compiledRules {
+		if index := rs.ruleIndex(userID, compiled.rule.ID); index != -1 {
+			rs.rules[userID][index] = compiled
+		} else {
+			rs.rules[userID] = append(rs.rules[userID], compiled)
+		}
+	}
+
+	return len(compiledRules), nil
+}
+
+// DeleteUserData удаляет правила пользователя
+func (rs *RulesService) DeleteUserData(userID string) {
+	rs.mux.Lock()
+	defer rs.mux.Unlock()
+
+	delete(rs.rules, userID)
+}
+
+// GetBackupFileName возвращает имя файла для бэкапа
+func (rs *RulesService) GetBackupFileName() string {
+	return "rules"
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"spendings-backend/internal/models"
+)
+
+func TestApplyRulesSkipsIncome(t *testing.T) {
+	march := func(day int) time.Time {
+		return time.Date(2026, time.March, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	categories := NewCategoriesService(map[string][]models.Category{
+		"rules": {{Name: "Фриланс", Type: models.CategoryTypeIncome}},
+	}, models.GetDefaultBaseCategories())
+	// Правило по сумме подходит и к расходам, и к доходам
+	rules := NewRulesService(map[string][]models.Rule{
+		"rules": {{ID: "large", MinAmount: amountOf(500), Category: "Развлечения", Tags: []string{"крупное"}}},
+	})
+	ts := NewTransactionsService(map[string]map[string]models.Transaction{"rules": {
+		"salary":    {ID: "salary", Title: "Зарплата", Category: models.IncomeCategory, Amount: 100000, Date: march(5)},
+		"freelance": {ID: "freelance", Title: "Заказ", Category: "Фриланс", Amount: 30000, Date: march(6)},
+		"cafe":      {ID: "cafe", Title: "Кафе", Category: "Еда", Amount: 1500, Date: march(7)},
+	}}, categories, rules, nil, time.Hour)
+	ctx := userContext("rules")
+
+	for _, dryRun := range []bool{true, false} {
+		result, err := ts.ApplyRules(ctx, dryRun)
+		require.NoError(t, err)
+		require.Len(t, result.Changes, 1, "dryRun=%t", dryRun)
+		assert.Equal(t, "cafe", result.Changes[0].TransactionID)
+		assert.Equal(t, "Развлечения", result.Changes[0].NewCategory)
+	}
+
+	transactions, err := ts.GetAllTransactions(ctx, time.Time{}, time.Time{})
+	require.NoError(t, err)
+
+	got := make(map[string]string, len(transactions))
+	for _, transaction := range transactions {
+		got[transaction.ID] = transaction.Category
+	}
+	assert.Equal(t, map[string]string{"salary": models.IncomeCategory, "freelance": "Фриланс", "cafe": "Развлечения"}, got)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"spendings-backend/internal/models"
+)
+
+// searchIndex инвертированный индекс для полнотекстового поиска по транзакциям пользователя.
+// Не потокобезопасен, доступ защищается мьютексом шарда пользователя.
+type searchIndex struct {
+	postings  map[string]map[string]struct{} // token -> transactionIDs
+	documents map[string][]string            // transactionID -> tokens
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings:  make(map[string]map[string]struct{}),
+		documents: make(map[string][]string),
+	}
+}
+
+// add индексирует транзакцию, заменяя предыдущую версию с тем же ID
+func (si *searchIndex) add(transaction models.Transaction) {
+	si.remove(transaction.ID)
+
+	tokens := tokenize(searchableText(transaction))
+	for _, token := range tokens {
+		if si.postings[token] == nil {
+			si.postings[token] = make(map[string]struct{})
+		}
+		si.postings[token][transaction.ID] = struct{}{}
+	}
+	si.documents[transaction.ID] = tokens
+}
+
+// remove удаляет транзакцию из индекса
+func (si *searchIndex) remove(transactionID string) {
+	for _, token := range si.documents[transactionID] {
+		delete(si.postings[token], transactionID)
+		if len(si.postings[token]) == 0 {
+			delete(si.postings, token)
+		}
+	}
+	delete(si.documents, transactionID)
+}
+
+// search возвращает ID транзакций, в которых каждое слово запроса
+// встречается как подстрока хотя бы одного проиндексированного слова
+func (si *searchIndex) search(query string) map[string]struct{} {
+	result := make(map[string]struct{})
+
+	queryTokens := tokenize(query)
+	if len(queryTokens) == 0 {
+		return result
+	}
+
+	for i, queryToken := range queryTokens {
+		matched := make(map[string]struct{})
+		for token, ids := range si.postings {
+			if !strings.Contains(token, queryToken) {
+				continue
+			}
+			for id := range ids {
+				matched[id] = struct{}{}
+			}
+		}
+
+		if i == 0 {
+			result = matched
+			continue
+		}
+
+		for id := range result {
+			if _, ok := matched[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+
+	return result
+}
+
+// searchableText возвращает текст транзакции, по которому выполняется поиск: название и заметку
+func searchableText(transaction models.Transaction) string {
+	if transaction.Note == "" {
+		return transaction.Title
+	}
+
+	return transaction.Title + " " + transaction.Note
+}
+
+// tokenize приводит текст к нижнему регистру, заменяет "ё" на "е"
+// и разбивает его на слова из букв и цифр
+func tokenize(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]struct{}, len(fields))
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if _, ok := seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		tokens = append(tokens, field)
+	}
+
+	return tokens
+}
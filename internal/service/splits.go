@@ -0,0 +1,85 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errTooFewSplitLines    = errors.New("split must have at least two lines")
+	errInvalidSplitLine    = errors.New("invalid split line")
+	errSplitIncomeCategory = errors.New("split lines cannot use income categories")
+	errSplitSumMismatch    = errors.New("split lines must sum to the transaction amount")
+	errSplitCategory       = errors.New("category must be one of the split line categories")
+)
+
+// normalizeSplits проверяет строки разбивки транзакции и возвращает их вместе с основной
+// категорией транзакции. Основной становится категория самой крупной строки, если
+// category не указана. Строки с одной категорией объединяются, строки с категориями доходов
+// income не допускаются.
+func normalizeSplits(splits []models.TransactionSplit, amount float64, category string, income incomeCategories) ([]models.TransactionSplit, string, error) {
+	if len(splits) == 0 {
+		return nil, category, nil
+	}
+
+	amounts := make(map[string]int64, len(splits))
+	var total int64
+	for i, split := range splits {
+		name := strings.TrimSpace(split.Category)
+		if name == "" || split.Amount <= 0 {
+			return nil, "", fmt.Errorf("%w %d: category must not be empty and amount must be positive", errInvalidSplitLine, i+1)
+		}
+		if income.isIncome(name) {
+			return nil, "", fmt.Errorf("%w: %s", errSplitIncomeCategory, name)
+		}
+
+		cents := int64(math.Round(split.Amount * 100))
+		amounts[name] += cents
+		total += cents
+	}
+
+	if len(amounts) < 2 {
+		return nil, "", errTooFewSplitLines
+	}
+	if total != int64(math.Round(amount*100)) {
+		return nil, "", fmt.Errorf("%w: %.2f != %.2f", errSplitSumMismatch, float64(total)/100, amount)
+	}
+
+	normalized := make([]models.TransactionSplit, 0, len(amounts))
+	for name, cents := range amounts {
+		normalized = append(normalized, models.TransactionSplit{Category: name, Amount: float64(cents) / 100})
+	}
+
+	// Крупные строки первыми, чтобы порядок не зависел от порядка в запросе
+	slices.SortFunc(normalized, func(a, b models.TransactionSplit) int {
+		if c := cmp.Compare(b.Amount, a.Amount); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Category, b.Category)
+	})
+
+	if category == "" {
+		return normalized, normalized[0].Category, nil
+	}
+	if _, exists := amounts[category]; !exists {
+		return nil, "", fmt.Errorf("%w: %s", errSplitCategory, category)
+	}
+
+	return normalized, category, nil
+}
+
+// categoryLines возвращает суммы транзакции по категориям: строки разбивки
+// или одну строку с категорией и суммой транзакции
+func categoryLines(transaction models.Transaction) []models.TransactionSplit {
+	if len(transaction.Splits) > 0 {
+		return transaction.Splits
+	}
+
+	return []models.TransactionSplit{{Category: transaction.Category, Amount: transaction.Amount}}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"spendings-backend/internal/models"
+)
+
+func TestSplitsRejectIncomeCategories(t *testing.T) {
+	categories := NewCategoriesService(map[string][]models.Category{
+		"splits": {{Name: "Фриланс", Type: models.CategoryTypeIncome}},
+	}, models.GetDefaultBaseCategories())
+	ts := NewTransactionsService(map[string]map[string]models.Transaction{"splits": {}}, categories, NewRulesService(nil), nil, time.Hour)
+	ctx := userContext("splits")
+
+	request := func(category string) models.CreateTransactionRequest {
+		return models.CreateTransactionRequest{
+			Amount: 1500,
+			Title:  "Перекресток",
+			Date:   "2026-03-01",
+			Splits: []models.TransactionSplit{{Category: "Еда", Amount: 1000}, {Category: category, Amount: 500}},
+		}
+	}
+
+	for _, category := range []string{models.IncomeCategory, "Фриланс"} {
+		_, err := ts.CreateTransaction(ctx, request(category))
+		assert.ErrorIs(t, err, models.ErrBadRequest, category)
+		assert.ErrorIs(t, err, errSplitIncomeCategory, category)
+
+		_, _, err = ts.ImportUserTransactions(ctx, map[string]models.Transaction{"1": {
+			Amount: 1500,
+			Title:  "Перекресток",
+			Date:   time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+			Splits: request(category).Splits,
+		}}, nil, false)
+		assert.ErrorIs(t, err, errSplitIncomeCategory, category)
+	}
+
+	_, err := ts.CreateTransaction(ctx, request("Хозтовары"))
+	require.NoError(t, err)
+
+	transactions, err := ts.GetAllTransactions(ctx, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "Еда", transactions[0].Category)
+}
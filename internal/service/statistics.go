@@ -1,35 +1,84 @@
 package service
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"spendings-backend/internal/models"
 )
 
+//go:generate mockgen -source=statistics.go -destination=mocks/statistics.go -package=mocks
+
 type TransactionsProvider interface {
 	GetAllTransactions(ctx context.Context, fromDate, toDate time.Time) ([]models.Transaction, error)
 }
 
 
+type CategoriesProvider interface {
+	GetCategoryParents(ctx context.Context) (map[string]string, error)
+	GetCategoryNames(ctx context.Context) (map[string]string, error)
+	GetIncomeCategories(ctx context.Context) (map[string]struct{}, error)
+}
+
+// incomeCategories категории доходов пользователя под названиями, под которыми они хранятся в транзакциях
+type incomeCategories map[string]struct{}
+
+// isIncome проверяет, относится ли категория к доходам. Базовая категория доходов
+// считается доходом, даже если ее нет в списке.
+func (ic incomeCategories) isIncome(category string) bool {
+	_, exists := ic[category]
+	return exists || category == models.IncomeCategory
+}
+
+// categoryNames новые названия базовых категорий, переименованных пользователем: исходное -> новое
+type categoryNames map[string]string
+
+// display возвращает название, под которым пользователь видит категорию
+func (cn categoryNames) display(category string) string {
+	if name, exists := cn[category]; exists {
+		return name
+	}
+
+	return category
+}
+
+// transaction возвращает копию транзакции с категориями под названиями пользователя
+func (cn categoryNames) transaction(transaction models.Transaction) models.Transaction {
+	transaction.Category = cn.display(transaction.Category)
+	if len(transaction.Splits) > 0 {
+		splits := make([]models.TransactionSplit, len(transaction.Splits))
+		for i, split := range transaction.Splits {
+			splits[i] = models.TransactionSplit{Category: cn.display(split.Category), Amount: split.Amount}
+		}
+		transaction.Splits = splits
+	}
+
+	return transaction
+}
+
+type PayeesProvider interface {
+	GetPayees(ctx context.Context) ([]models.Payee, error)
+}
+
 type StatisticsService struct {
 	transactionsService TransactionsProvider
+	categoriesService   CategoriesProvider
+	payeesService       PayeesProvider
 }
 
-func NewStatisticsService(transactionsService TransactionsProvider) *StatisticsService {
+func NewStatisticsService(transactionsService TransactionsProvider, categoriesService CategoriesProvider, payeesService PayeesProvider) *StatisticsService {
 	return &StatisticsService{
 		transactionsService: transactionsService,
+		categoriesService:   categoriesService,
+		payeesService:       payeesService,
 	}
 }
 
 func (ss *StatisticsService) GetStatistics(ctx context.Context, fromDate, toDate time.Time) (*models.StatisticsResponse, error) {
-	// Если даты не указаны, используем текущий месяц
-	if fromDate.IsZero() && toDate.IsZero() {
-		now := time.Now()
-		fromDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-		toDate = fromDate.AddDate(0, 1, -1) // последний день месяца
-	}
+	fromDate, toDate = statisticsPeriod(fromDate, toDate)
 
 	// Получаем все транзакции пользователя за период
 	transactions, err := ss.transactionsService.GetAllTransactions(ctx, fromDate, toDate)
@@ -37,33 +86,115 @@ func (ss *StatisticsService) GetStatistics(ctx context.Context, fromDate, toDate
 		return nil, fmt.Errorf("failed to get transactions: %w", err)
 	}
 
+	income, err := ss.incomeCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Вычисляем общую статистику
-	generalStats := ss.calculateGeneralStatistics(transactions)
+	generalStats := ss.calculateGeneralStatistics(transactions, income)
 
 	// Вычисляем изменения баланса по датам
-	balanceChanges := ss.calculateBalanceChangesByDate(transactions, fromDate, toDate)
+	balanceChanges := ss.calculateBalanceChangesByDate(transactions, income, fromDate, toDate)
 
 	// Вычисляем информацию о кривой трат
-	spendingCurve, err := ss.calculateSpendingCurve(ctx, transactions, fromDate, toDate)
+	spendingCurve := ss.calculateSpendingCurve(transactions, income, fromDate, toDate)
+
+	// Вычисляем расходы по категориям и сворачиваем подкатегории в родителей
+	expensesByCategory := ss.calculateExpensesByCategory(transactions, income)
+
+	parents, err := ss.categoriesService.GetCategoryParents(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate spending curve: %w", err)
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	categoryBreakdown := calculateCategoryBreakdown(expensesByCategory, parents)
+
+	// Переименованные пользователем базовые категории показываем под новыми названиями
+	names, err := ss.categoriesService.GetCategoryNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	expensesByCategory = renameExpensesByCategory(expensesByCategory, names)
+	renameCategoryBreakdown(categoryBreakdown, names)
+
+	// Вычисляем итоги по тегам
+	tagTotals := ss.calculateTagTotals(transactions, income)
+
+	// Крупнейшие получатели - только те, у кого были расходы
+	payees, err := ss.payeesService.GetPayees(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payees: %w", err)
+	}
+	topMerchants := calculatePayeeStatistics(transactions, income, newPayeeMatcher(payees))
+	topMerchants = slices.DeleteFunc(topMerchants, func(stats models.PayeeStatistics) bool {
+		return stats.Expenses == 0
+	})
+	if len(topMerchants) > models.DefaultTopMerchantsLimit {
+		topMerchants = topMerchants[:models.DefaultTopMerchantsLimit]
 	}
 
 	return &models.StatisticsResponse{
 		GeneralStatistics:    generalStats,
 		BalanceChangesByDate: balanceChanges,
 		SpendingCurveInfo:    spendingCurve,
+		ExpensesByCategory:   expensesByCategory,
+		CategoryBreakdown:    categoryBreakdown,
+		TagTotals:            tagTotals,
+		TopMerchants:         topMerchants,
 		FromDate:             fromDate.Format("2006-01-02"),
 		ToDate:               toDate.Format("2006-01-02"),
 	}, nil
 }
 
+// GetPayeeStatistics возвращает доходы и расходы по всем получателям за период
+func (ss *StatisticsService) GetPayeeStatistics(ctx context.Context, fromDate, toDate time.Time) ([]models.PayeeStatistics, error) {
+	fromDate, toDate = statisticsPeriod(fromDate, toDate)
+
+	transactions, err := ss.transactionsService.GetAllTransactions(ctx, fromDate, toDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	income, err := ss.incomeCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	payees, err := ss.payeesService.GetPayees(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payees: %w", err)
+	}
+
+	return calculatePayeeStatistics(transactions, income, newPayeeMatcher(payees)), nil
+}
+
+// incomeCategories возвращает категории доходов текущего пользователя
+func (ss *StatisticsService) incomeCategories(ctx context.Context) (incomeCategories, error) {
+	income, err := ss.categoriesService.GetIncomeCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	return income, nil
+}
+
+// statisticsPeriod возвращает период статистики, по умолчанию - текущий месяц
+func statisticsPeriod(fromDate, toDate time.Time) (time.Time, time.Time) {
+	if fromDate.IsZero() && toDate.IsZero() {
+		now := time.Now()
+		fromDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		toDate = fromDate.AddDate(0, 1, -1) // последний день месяца
+	}
+
+	return fromDate, toDate
+}
+
 // calculateGeneralStatistics вычисляет общую статистику
-func (ss *StatisticsService) calculateGeneralStatistics(transactions []models.Transaction) models.GeneralStatistics {
+func (ss *StatisticsService) calculateGeneralStatistics(transactions []models.Transaction, incomeCategories incomeCategories) models.GeneralStatistics {
 	var income, expenses float64
 
 	for _, transaction := range transactions {
-		if transaction.Category == models.IncomeCategory {
+		if incomeCategories.isIncome(transaction.Category) {
 			income += transaction.Amount
 		} else {
 			expenses += transaction.Amount
@@ -79,8 +210,215 @@ func (ss *StatisticsService) calculateGeneralStatistics(transactions []models.Tr
 	}
 }
 
+// calculateExpensesByCategory вычисляет расходы по категориям, распределяя разбитые транзакции по строкам
+func (ss *StatisticsService) calculateExpensesByCategory(transactions []models.Transaction, income incomeCategories) map[string]float64 {
+	expenses := make(map[string]float64)
+
+	for _, transaction := range transactions {
+		for _, line := range categoryLines(transaction) {
+			if !income.isIncome(line.Category) {
+				expenses[line.Category] += line.Amount
+			}
+		}
+	}
+
+	return expenses
+}
+
+// calculateCategoryBreakdown строит дерево расходов по категориям: сумма категории
+// включает суммы всех ее подкатегорий. Крупные категории идут первыми на каждом уровне.
+func calculateCategoryBreakdown(expensesByCategory map[string]float64, parents map[string]string) []models.CategoryStatistics {
+	type node struct {
+		stats    models.CategoryStatistics
+		children []string
+	}
+
+	nodes := make(map[string]*node)
+	getNode := func(category string) *node {
+		if nodes[category] == nil {
+			nodes[category] = &node{stats: models.CategoryStatistics{Category: category}}
+		}
+		return nodes[category]
+	}
+
+	for category, amount := range expensesByCategory {
+		current := getNode(category)
+		current.stats.OwnAmount += amount
+		current.stats.Amount += amount
+
+		// Поднимаемся к корню, добавляя сумму предкам; visited защищает от циклов
+		visited := map[string]struct{}{category: {}}
+		for parent, exists := parents[category]; exists; parent, exists = parents[parent] {
+			if _, seen := visited[parent]; seen {
+				break
+			}
+			visited[parent] = struct{}{}
+
+			parentNode := getNode(parent)
+			parentNode.stats.Amount += amount
+			if !slices.Contains(parentNode.children, current.stats.Category) {
+				parentNode.children = append(parentNode.children, current.stats.Category)
+			}
+			current = parentNode
+		}
+	}
+
+	var build func(names []string) []models.CategoryStatistics
+	build = func(names []string) []models.CategoryStatistics {
+		level := make([]models.CategoryStatistics, 0, len(names))
+		for _, name := range names {
+			stats := nodes[name].stats
+			stats.Children = build(nodes[name].children)
+			level = append(level, stats)
+		}
+
+		slices.SortFunc(level, func(a, b models.CategoryStatistics) int {
+			if c := cmp.Compare(b.Amount, a.Amount); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Category, b.Category)
+		})
+
+		return level
+	}
+
+	var roots []string
+	for name := range nodes {
+		if _, hasParent := nodes[parents[name]]; !hasParent {
+			roots = append(roots, name)
+		}
+	}
+
+	return build(roots)
+}
+
+// renameExpensesByCategory заменяет в ключах исходные названия категорий на названия пользователя
+func renameExpensesByCategory(expensesByCategory map[string]float64, names map[string]string) map[string]float64 {
+	renamed := make(map[string]float64, len(expensesByCategory))
+	for category, amount := range expensesByCategory {
+		if name, exists := names[category]; exists {
+			category = name
+		}
+		renamed[category] += amount
+	}
+
+	return renamed
+}
+
+func renameCategoryBreakdown(breakdown []models.CategoryStatistics, names map[string]string) {
+	for i := range breakdown {
+		if name, exists := names[breakdown[i].Category]; exists {
+			breakdown[i].Category = name
+		}
+		renameCategoryBreakdown(breakdown[i].Children, names)
+	}
+}
+
+// calculateTagTotals вычисляет доходы и расходы по тегам, теги с большими расходами идут первыми
+func (ss *StatisticsService) calculateTagTotals(transactions []models.Transaction, income incomeCategories) []models.TagStatistics {
+	totals := make(map[string]*models.TagStatistics)
+
+	for _, transaction := range transactions {
+		for _, tag := range transaction.Tags {
+			tagTotal, exists := totals[tag]
+			if !exists {
+				tagTotal = &models.TagStatistics{Tag: tag}
+				totals[tag] = tagTotal
+			}
+
+			if income.isIncome(transaction.Category) {
+				tagTotal.Income += transaction.Amount
+			} else {
+				tagTotal.Expenses += transaction.Amount
+			}
+			tagTotal.Count++
+		}
+	}
+
+	tagTotals := make([]models.TagStatistics, 0, len(totals))
+	for _, tagTotal := range totals {
+		tagTotals = append(tagTotals, *tagTotal)
+	}
+
+	slices.SortFunc(tagTotals, func(a, b models.TagStatistics) int {
+		if c := cmp.Compare(b.Expenses, a.Expenses); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+
+	return tagTotals
+}
+
+// calculatePayeeStatistics группирует транзакции по получателям. Получатели с большими
+// расходами идут первыми. Название получателя, найденного только по названиям транзакций,
+// берется из самого частого названия.
+func calculatePayeeStatistics(transactions []models.Transaction, income incomeCategories, matcher *payeeMatcher) []models.PayeeStatistics {
+	type payeeTotal struct {
+		stats  models.PayeeStatistics
+		titles map[string]int
+	}
+
+	totals := make(map[string]*payeeTotal)
+	for _, transaction := range transactions {
+		key, payee := matcher.match(transaction.Title)
+
+		total, exists := totals[key]
+		if !exists {
+			total = &payeeTotal{titles: make(map[string]int)}
+			if payee != nil {
+				total.stats.PayeeID = payee.ID
+				total.stats.Name = payee.Name
+			}
+			totals[key] = total
+		}
+
+		if income.isIncome(transaction.Category) {
+			total.stats.Income += transaction.Amount
+		} else {
+			total.stats.Expenses += transaction.Amount
+		}
+		total.stats.Count++
+		if date := transaction.Date.Format("2006-01-02"); date > total.stats.LastDate {
+			total.stats.LastDate = date
+		}
+		total.titles[transaction.Title]++
+	}
+
+	payeeStats := make([]models.PayeeStatistics, 0, len(totals))
+	for _, total := range totals {
+		stats := total.stats
+		stats.Titles = make([]string, 0, len(total.titles))
+		for title := range total.titles {
+			stats.Titles = append(stats.Titles, title)
+		}
+		slices.SortFunc(stats.Titles, func(a, b string) int {
+			if c := cmp.Compare(total.titles[b], total.titles[a]); c != 0 {
+				return c
+			}
+			return cmp.Compare(a, b)
+		})
+		if stats.Name == "" {
+			stats.Name = stats.Titles[0]
+		}
+		payeeStats = append(payeeStats, stats)
+	}
+
+	slices.SortFunc(payeeStats, func(a, b models.PayeeStatistics) int {
+		if c := cmp.Compare(b.Expenses, a.Expenses); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Income, a.Income); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return payeeStats
+}
+
 // calculateBalanceChangesByDate вычисляет изменения баланса по датам
-func (ss *StatisticsService) calculateBalanceChangesByDate(transactions []models.Transaction, fromDate, toDate time.Time) map[string]float64 {
+func (ss *StatisticsService) calculateBalanceChangesByDate(transactions []models.Transaction, income incomeCategories, fromDate, toDate time.Time) map[string]float64 {
 	balanceChanges := make(map[string]float64)
 
 	// Инициализируем все даты в периоде нулевыми значениями
@@ -94,7 +432,7 @@ func (ss *StatisticsService) calculateBalanceChangesByDate(transactions []models
 	// Добавляем изменения от транзакций
 	for _, transaction := range transactions {
 		dateStr := transaction.Date.Format("2006-01-02")
-		if transaction.Category == models.IncomeCategory {
+		if income.isIncome(transaction.Category) {
 			balanceChanges[dateStr] += transaction.Amount
 		} else {
 			balanceChanges[dateStr] -= transaction.Amount
@@ -105,26 +443,14 @@ func (ss *StatisticsService) calculateBalanceChangesByDate(transactions []models
 }
 
 // calculateSpendingCurve вычисляет информацию о кривой трат
-func (ss *StatisticsService) calculateSpendingCurve(ctx context.Context, transactions []models.Transaction, fromDate, toDate time.Time) ([]models.SpendingCurveInfo, error) {
-	// Получаем все траты пользователя для вычисления средних значений
-	allExpenses, err := ss.transactionsService.GetAllTransactions(ctx, time.Time{}, time.Time{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get all transactions: %w", err)
-	}
-
-	// Фильтруем только траты (не доходы)
-	var allExpenseTransactions []models.Transaction
-	for _, transaction := range allExpenses {
-		if transaction.Category != models.IncomeCategory {
-			allExpenseTransactions = append(allExpenseTransactions, transaction)
-		}
-	}
-
+func (ss *StatisticsService) calculateSpendingCurve(transactions []models.Transaction, income incomeCategories, fromDate, toDate time.Time) []models.SpendingCurveInfo {
 	// Группируем траты по датам
 	expensesByDate := make(map[string]float64)
-	for _, transaction := range allExpenseTransactions {
-		dateStr := transaction.Date.Format("2006-01-02")
-		expensesByDate[dateStr] += transaction.Amount
+	for _, transaction := range transactions {
+		if !income.isIncome(transaction.Category) {
+			dateStr := transaction.Date.Format("2006-01-02")
+			expensesByDate[dateStr] += transaction.Amount
+		}
 	}
 
 	// Группируем транзакции текущего периода по датам
@@ -150,7 +476,7 @@ func (ss *StatisticsService) calculateSpendingCurve(ctx context.Context, transac
 		currentSpending := 0.0
 		if dayTransactions, exists := transactionsByDate[dateStr]; exists {
 			for _, transaction := range dayTransactions {
-				if transaction.Category != models.IncomeCategory {
+				if !income.isIncome(transaction.Category) {
 					currentSpending += transaction.Amount
 				}
 			}
@@ -170,5 +496,5 @@ func (ss *StatisticsService) calculateSpendingCurve(ctx context.Context, transac
 		})
 	}
 
-	return spendingCurve, nil
+	return spendingCurve
 }
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"spendings-backend/internal/models"
+)
+
+// suggestIndex наивный байесовский классификатор категорий по словам названия
+// и порядку суммы. Обучается на транзакциях пользователя по мере их добавления.
+// Не потокобезопасен, доступ защищается мьютексом шарда пользователя.
+type suggestIndex struct {
+	documents  map[string]int            // category -> количество транзакций
+	features   map[string]map[string]int // category -> feature -> количество
+	totals     map[string]int            // category -> количество признаков
+	vocabulary map[string]int            // feature -> количество во всех категориях
+	total      int                       // количество транзакций
+}
+
+func newSuggestIndex() *suggestIndex {
+	return &suggestIndex{
+		documents:  make(map[string]int),
+		features:   make(map[string]map[string]int),
+		totals:     make(map[string]int),
+		vocabulary: make(map[string]int),
+	}
+}
+
+func (si *suggestIndex) add(transaction models.Transaction) {
+	category := transaction.Category
+	if category == "" {
+		return
+	}
+
+	amount := transaction.Amount
+	features := suggestFeatures(transaction.Title, &amount)
+
+	if si.features[category] == nil {
+		si.features[category] = make(map[string]int)
+	}
+
+	si.documents[category]++
+	si.total++
+	for _, feature := range features {
+		si.features[category][feature]++
+		si.totals[category]++
+		si.vocabulary[feature]++
+	}
+}
+
+func (si *suggestIndex) remove(transaction models.Transaction) {
+	category := transaction.Category
+	if si.documents[category] == 0 {
+		return
+	}
+
+	amount := transaction.Amount
+	features := suggestFeatures(transaction.Title, &amount)
+
+	si.documents[category]--
+	si.total--
+	for _, feature := range features {
+		decrement(si.features[category], feature)
+		decrement(si.vocabulary, feature)
+		si.totals[category]--
+	}
+
+	if si.documents[category] == 0 {
+		delete(si.documents, category)
+		delete(si.features, category)
+		delete(si.totals, category)
+	}
+}
+
+// suggest возвращает категории в порядке убывания вероятности. Используется
+// мультиномиальная модель со сглаживанием Лапласа, вероятности считаются в логарифмах.
+func (si *suggestIndex) suggest(title string, amount *float64, limit int) []models.CategorySuggestion {
+	suggestions := make([]models.CategorySuggestion, 0, len(si.documents))
+	if si.total == 0 {
+		return suggestions
+	}
+
+	features := suggestFeatures(title, amount)
+	vocabularySize := float64(len(si.vocabulary) + 1)
+
+	scores := make(map[string]float64, len(si.documents))
+	best := math.Inf(-1)
+	for category, documents := range si.documents {
+		score := math.Log(float64(documents) / float64(si.total))
+		for _, feature := range features {
+			score += math.Log(float64(si.features[category][feature]+1) / (float64(si.totals[category]) + vocabularySize))
+		}
+		scores[category] = score
+		best = max(best, score)
+	}
+
+	// Переводим логарифмы в вероятности, вычитая максимум для устойчивости
+	sum := 0.0
+	for category, score := range scores {
+		scores[category] = math.Exp(score - best)
+		sum += scores[category]
+	}
+
+	for category, score := range scores {
+		suggestions = append(suggestions, models.CategorySuggestion{Category: category, Probability: score / sum})
+	}
+
+	slices.SortFunc(suggestions, func(a, b models.CategorySuggestion) int {
+		if c := cmp.Compare(b.Probability, a.Probability); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Category, b.Category)
+	})
+
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions
+}
+
+// suggestFeatures выделяет признаки транзакции: слова названия, в которых есть буквы
+// (номера магазинов и карт только мешают), и порядок суммы по степеням двойки
+func suggestFeatures(title string, amount *float64) []string {
+	tokens := tokenize(title)
+
+	features := make([]string, 0, len(tokens)+1)
+	for _, token := range tokens {
+		if strings.IndexFunc(token, unicode.IsLetter) != -1 {
+			features = append(features, token)
+		}
+	}
+
+	if amount != nil && *amount > 0 {
+		features = append(features, "#amount:"+strconv.Itoa(int(math.Floor(math.Log2(*amount)))))
+	}
+
+	return features
+}
+
+func decrement(counts map[string]int, key string) {
+	counts[key]--
+	if counts[key] <= 0 {
+		delete(counts, key)
+	}
+}
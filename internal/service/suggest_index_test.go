@@ -0,0 +1,163 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"spendings-backend/internal/models"
+)
+
+func newTrainedSuggestIndex(transactions ...models.Transaction) *suggestIndex {
+	index := newSuggestIndex()
+	for i, transaction := range transactions {
+		transaction.ID = string(rune('a' + i))
+		index.add(transaction)
+	}
+
+	return index
+}
+
+func suggestCategoriesOf(suggestions []models.CategorySuggestion) []string {
+	categories := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		categories = append(categories, suggestion.Category)
+	}
+
+	return categories
+}
+
+func amountOf(value float64) *float64 {
+	return &value
+}
+
+func TestSuggestRanking(t *testing.T) {
+	index := newTrainedSuggestIndex(
+		models.Transaction{Title: "Пятерочка продукты", Category: "Еда", Amount: 1200},
+		models.Transaction{Title: "Пятерочка", Category: "Еда", Amount: 900},
+		models.Transaction{Title: "Перекресток продукты", Category: "Еда", Amount: 2500},
+		models.Transaction{Title: "Такси домой", Category: "Транспорт", Amount: 450},
+		models.Transaction{Title: "Такси в аэропорт", Category: "Транспорт", Amount: 1800},
+		models.Transaction{Title: "Метро", Category: "Транспорт", Amount: 60},
+		models.Transaction{Title: "Кино", Category: "Развлечения", Amount: 700},
+		models.Transaction{Title: "Кино IMAX", Category: "Развлечения", Amount: 900},
+	)
+
+	cases := []struct {
+		title  string
+		amount *float64
+		want   string
+	}{
+		{title: "ПЯТЕРОЧКА №1234", want: "Еда"},
+		{title: "продукты", amount: amountOf(1500), want: "Еда"},
+		{title: "Такси", want: "Транспорт"},
+		{title: "Метро, пополнение", amount: amountOf(60), want: "Транспорт"},
+		{title: "Кино", want: "Развлечения"},
+	}
+
+	for _, tc := range cases {
+		suggestions := index.suggest(tc.title, tc.amount, 0)
+		require.Len(t, suggestions, 3, tc.title)
+		assert.Equal(t, tc.want, suggestions[0].Category, tc.title)
+
+		sum := 0.0
+		for i, suggestion := range suggestions {
+			sum += suggestion.Probability
+			if i > 0 {
+				assert.LessOrEqual(t, suggestion.Probability, suggestions[i-1].Probability, tc.title)
+			}
+		}
+		assert.InDelta(t, 1, sum, 1e-9, tc.title)
+	}
+
+	assert.Len(t, index.suggest("Такси", nil, 2), 2)
+}
+
+func TestSuggestProbabilities(t *testing.T) {
+	// Словарь из двух слов, сглаживание Лапласа: P(кофе|Кафе) = 2/4, P(кофе|Чай) = 1/4
+	index := newTrainedSuggestIndex(
+		models.Transaction{Title: "кофе", Category: "Кафе"},
+		models.Transaction{Title: "чай", Category: "Чай"},
+	)
+
+	suggestions := index.suggest("Кофе", nil, 0)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "Кафе", suggestions[0].Category)
+	assert.InDelta(t, 2.0/3, suggestions[0].Probability, 1e-9)
+	assert.InDelta(t, 1.0/3, suggestions[1].Probability, 1e-9)
+
+	// Незнакомое слово не меняет априорные вероятности, равные вероятности сортируются по названию
+	suggestions = index.suggest("сок", nil, 0)
+	assert.Equal(t, []string{"Кафе", "Чай"}, suggestCategoriesOf(suggestions))
+	assert.InDelta(t, 0.5, suggestions[0].Probability, 1e-9)
+}
+
+func TestSuggestAmountFeature(t *testing.T) {
+	// Одинаковые названия различаются только порядком суммы
+	index := newTrainedSuggestIndex(
+		models.Transaction{Title: "Перевод", Category: "Прочее", Amount: 100},
+		models.Transaction{Title: "Перевод", Category: "Прочее", Amount: 120},
+		models.Transaction{Title: "Перевод", Category: "Аренда", Amount: 30000},
+		models.Transaction{Title: "Перевод", Category: "Аренда", Amount: 32000},
+	)
+
+	assert.Equal(t, "Аренда", index.suggest("Перевод", amountOf(31000), 1)[0].Category)
+	assert.Equal(t, "Прочее", index.suggest("Перевод", amountOf(110), 1)[0].Category)
+}
+
+func TestSuggestSingleCategory(t *testing.T) {
+	index := newTrainedSuggestIndex(
+		models.Transaction{Title: "Пятерочка", Category: "Еда", Amount: 500},
+		models.Transaction{Title: "Перекресток", Category: "Еда", Amount: 800},
+	)
+
+	for _, title := range []string{"Пятерочка", "Такси"} {
+		suggestions := index.suggest(title, nil, 3)
+		require.Len(t, suggestions, 1, title)
+		assert.Equal(t, "Еда", suggestions[0].Category)
+		assert.InDelta(t, 1, suggestions[0].Probability, 1e-9)
+	}
+}
+
+func TestSuggestEmptyVocabulary(t *testing.T) {
+	// Пустой индекс ничего не предлагает
+	empty := newSuggestIndex()
+	suggestions := empty.suggest("Пятерочка", amountOf(100), 3)
+	assert.NotNil(t, suggestions)
+	assert.Empty(t, suggestions)
+
+	// Названия только из цифр без суммы не дают признаков: остаются априорные вероятности
+	index := newTrainedSuggestIndex(
+		models.Transaction{Title: "1234", Category: "Еда"},
+		models.Transaction{Title: "5678", Category: "Еда"},
+		models.Transaction{Title: "0000", Category: "Транспорт"},
+	)
+	require.Empty(t, index.vocabulary)
+
+	suggestions = index.suggest("Пятерочка", nil, 0)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "Еда", suggestions[0].Category)
+	assert.InDelta(t, 2.0/3, suggestions[0].Probability, 1e-9)
+	assert.InDelta(t, 1.0/3, suggestions[1].Probability, 1e-9)
+}
+
+func TestSuggestRemove(t *testing.T) {
+	transactions := []models.Transaction{
+		{ID: "1", Title: "Такси", Category: "Транспорт", Amount: 300},
+		{ID: "2", Title: "Пятерочка", Category: "Еда", Amount: 700},
+	}
+
+	index := newSuggestIndex()
+	for _, transaction := range transactions {
+		index.add(transaction)
+	}
+
+	index.remove(transactions[1])
+	assert.Equal(t, []string{"Транспорт"}, suggestCategoriesOf(index.suggest("Пятерочка", nil, 0)))
+
+	// Удаление последней транзакции возвращает индекс в исходное состояние
+	index.remove(transactions[0])
+	assert.Equal(t, newSuggestIndex(), index)
+	assert.Empty(t, index.suggest("Такси", nil, 0))
+}
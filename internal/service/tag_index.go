@@ -0,0 +1,119 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errEmptyTag         = errors.New("tag cannot be empty")
+	errTagTooLong       = errors.New("tag is too long")
+	errTooManyTags      = errors.New("too many tags")
+	errUnknownTagsMatch = errors.New("unknown tags match mode")
+)
+
+// tagIndex считает, сколько транзакций пользователя отмечено каждым тегом.
+// Не потокобезопасен, доступ защищается мьютексом шарда пользователя.
+type tagIndex struct {
+	counts map[string]int // tag -> количество транзакций
+}
+
+func newTagIndex() *tagIndex {
+	return &tagIndex{
+		counts: make(map[string]int),
+	}
+}
+
+func (ti *tagIndex) add(transaction models.Transaction) {
+	for _, tag := range transaction.Tags {
+		ti.counts[tag]++
+	}
+}
+
+func (ti *tagIndex) remove(transaction models.Transaction) {
+	for _, tag := range transaction.Tags {
+		ti.counts[tag]--
+		if ti.counts[tag] <= 0 {
+			delete(ti.counts, tag)
+		}
+	}
+}
+
+// list возвращает теги, начинающиеся с prefix, самые используемые первыми
+func (ti *tagIndex) list(prefix string, limit int) []models.TagUsage {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+
+	tags := make([]models.TagUsage, 0)
+	for tag, count := range ti.counts {
+		if strings.HasPrefix(tag, prefix) {
+			tags = append(tags, models.TagUsage{Name: tag, Count: count})
+		}
+	}
+
+	slices.SortFunc(tags, func(a, b models.TagUsage) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	if limit > 0 && len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	return tags
+}
+
+// normalizeTags приводит теги к нижнему регистру, убирает повторы и сортирует их
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, errEmptyTag
+		}
+		if utf8.RuneCountInString(tag) > models.MaxTagLength {
+			return nil, fmt.Errorf("%w: %s, must be at most %d characters", errTagTooLong, tag, models.MaxTagLength)
+		}
+		normalized = append(normalized, tag)
+	}
+
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+
+	if len(normalized) > models.MaxTransactionTags {
+		return nil, fmt.Errorf("%w: must be at most %d", errTooManyTags, models.MaxTransactionTags)
+	}
+
+	return normalized, nil
+}
+
+// matchesTags проверяет теги транзакции: any - есть хотя бы один из тегов фильтра, all - есть все
+func matchesTags(transaction models.Transaction, tags []string, match string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+
+	if match == models.TagsMatchAll {
+		for _, tag := range tags {
+			if !slices.Contains(transaction.Tags, tag) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return slices.ContainsFunc(tags, func(tag string) bool {
+		return slices.Contains(transaction.Tags, tag)
+	})
+}
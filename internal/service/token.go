@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"crypto/rsa"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -63,6 +65,31 @@ func (t *TokenService) GenerateToken(ctx context.Context, username string, isTea
 	return tokenString, nil
 }
 
+// GetIssuedToken ищет токен в журнале созданных токенов
+func (t *TokenService) GetIssuedToken(id string) (*models.IssuedToken, error) {
+	data, err := os.ReadFile(t.keysListFilePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read created tokens: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// issuer;username;id;isTeacher, имя пользователя может содержать ';'
+		fields := strings.Split(strings.TrimSpace(line), ";")
+		if len(fields) < 4 || fields[len(fields)-2] != id {
+			continue
+		}
+
+		return &models.IssuedToken{
+			Issuer:    fields[0],
+			Nickname:  strings.Join(fields[1:len(fields)-2], ";"),
+			ID:        id,
+			IsTeacher: fields[len(fields)-1] == "true",
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: token %s not found", models.ErrNotFound, id)
+}
+
 func AppendFile(filename string, data []byte, perm os.FileMode) error {
 	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, perm)
 	if err != nil {
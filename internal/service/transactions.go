@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"sort"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,211 +15,495 @@ import (
 	"spendings-backend/internal/models"
 )
 
+// streamBatchSize количество транзакций, читаемых за одну блокировку шарда при выгрузке
+const streamBatchSize = 500
+
+// CategoryResolver переводит название категории из запроса в название, под которым она хранится в транзакциях,
+// и обратно, и возвращает категории доходов пользователя
+type CategoryResolver interface {
+	ResolveCategory(ctx context.Context, name string) string
+	GetCategoryNames(ctx context.Context) (map[string]string, error)
+	GetIncomeCategories(ctx context.Context) (map[string]struct{}, error)
+}
+
 type TransactionsService struct {
-	transactions map[string]map[string]models.Transaction // userID -> transactionID -> transaction
-	mux          sync.RWMutex
+	shards      map[string]*userShard // userID -> транзакции пользователя
+	mux         sync.RWMutex          // защищает только map шардов
+	categories  CategoryResolver
+	rules       RulesMatcher
+	attachments AttachmentsStore
+	// trashRetention время, через которое транзакции из корзины удаляются навсегда
+	trashRetention time.Duration
 }
 
-func NewTransactionsService(initialData map[string]map[string]models.Transaction) *TransactionsService {
-	ts := &TransactionsService{}
+func NewTransactionsService(
+	initialData map[string]map[string]models.Transaction,
+	categories CategoryResolver,
+	rules RulesMatcher,
+	attachments AttachmentsStore,
+	trashRetention time.Duration,
+) *TransactionsService {
+	ts := &TransactionsService{
+		shards:         make(map[string]*userShard, len(initialData)),
+		categories:     categories,
+		rules:          rules,
+		attachments:    attachments,
+		trashRetention: trashRetention,
+	}
+
+	for userID, userTransactions := range initialData {
+		shard := newUserShard()
+		// Пользователи из сохраненных данных не получают начальные транзакции повторно
+		shard.seedOnce.Do(func() {})
 
-	if initialData != nil {
-		ts.transactions = initialData
-	} else {
-		ts.transactions = make(map[string]map[string]models.Transaction)
+		for _, transaction := range userTransactions {
+			// Удаленные транзакции хранятся в бэкапе вместе с остальными
+			if transaction.DeletedAt != nil {
+				shard.trash[transaction.ID] = transaction
+				continue
+			}
+			shard.put(transaction)
+		}
+
+		ts.shards[userID] = shard
 	}
 
 	return ts
 }
 
-func (ts *TransactionsService) GetTransactions(ctx context.Context, categories []string, fromDate, toDate time.Time, page, pageSize int) (*models.TransactionsResponse, error) {
+func (ts *TransactionsService) GetTransactions(ctx context.Context, filter models.TransactionsFilter, pagination models.TransactionsPagination) (*models.TransactionsResponse, error) {
 	userID := models.ClaimsFromContext(ctx).ID
 
-	ts.mux.RLock()
+	var (
+		order     transactionsOrder
+		cursorKey models.Transaction
+		err       error
+	)
+
+	if filter, err = ts.normalizeFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+
+	if pagination.Cursor != "" {
+		order, cursorKey, err = decodeCursor(pagination.Cursor)
+	} else {
+		order, err = newTransactionsOrder(pagination.SortBy, pagination.Order)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrBadRequest, err)
+	}
+
+	shard := ts.userShard(userID)
 
-	userTransactions, exists := ts.transactions[userID]
-	if !exists {
-		ts.mux.RUnlock()
-		ts.mux.Lock()
-		ts.transactions[userID] = getInitialTransactions()
-		ts.mux.Unlock()
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	// Без текстового поиска сортировка по дате выполняется по индексу дат
+	if strings.TrimSpace(filter.Query) == "" && order.sortBy == models.SortByDate {
+		return paginateByDate(shard, filter, order, cursorKey, pagination), nil
 	}
 
-	ts.mux.RLock()
-	defer ts.mux.RUnlock()
+	filteredTransactions := shard.selectTransactions(filter)
+	slices.SortFunc(filteredTransactions, order.compare)
 
-	// Конвертируем map в slice и применяем фильтры
-	var filteredTransactions []models.Transaction
-	for _, transaction := range userTransactions {
-		// Фильтр по датам
-		if !fromDate.IsZero() && transaction.Date.Before(fromDate) {
-			continue
+	transactionsAmount := len(filteredTransactions)
+
+	// В режиме курсора начинаем сразу после последней отданной транзакции
+	paginationStart := (pagination.Page - 1) * pagination.PageSize
+	if pagination.Cursor != "" {
+		paginationStart, _ = slices.BinarySearchFunc(filteredTransactions, cursorKey, order.compare)
+		if paginationStart < transactionsAmount && order.compare(filteredTransactions[paginationStart], cursorKey) == 0 {
+			paginationStart++
+		}
+	}
+
+	paginationStart = min(paginationStart, transactionsAmount)
+	paginationEnd := min(paginationStart+pagination.PageSize, transactionsAmount)
+
+	paginatedTransactions := make([]models.Transaction, 0, paginationEnd-paginationStart)
+	paginatedTransactions = append(paginatedTransactions, filteredTransactions[paginationStart:paginationEnd]...)
+
+	return newTransactionsResponse(pagination, order, transactionsAmount, paginatedTransactions, paginationEnd < transactionsAmount), nil
+}
+
+// paginateByDate возвращает страницу транзакций, отсортированных по дате, обходя индекс дат.
+// Без фильтров по категориям и сумме страница выбирается за O(log n + pageSize),
+// иначе просматривается только диапазон дат из фильтра. Вызывается под блокировкой шарда на чтение.
+func paginateByDate(
+	shard *userShard,
+	filter models.TransactionsFilter,
+	order transactionsOrder,
+	cursorKey models.Transaction,
+	pagination models.TransactionsPagination,
+) *models.TransactionsResponse {
+	entries := shard.dateIndex.rangeOf(filter.FromDate, filter.ToDate)
+
+	at := func(i int) models.Transaction {
+		if order.desc {
+			return shard.transactions[entries[len(entries)-1-i].id]
 		}
-		if !toDate.IsZero() && transaction.Date.After(toDate) {
+		return shard.transactions[entries[i].id]
+	}
+
+	// В режиме курсора пропускаем записи индекса до курсора,
+	// в режиме страниц - подходящие под фильтры транзакции предыдущих страниц
+	skipEntries, skipMatched := 0, (pagination.Page-1)*pagination.PageSize
+	if pagination.Cursor != "" {
+		skipEntries, skipMatched = positionAfter(entries, cursorKey, order.desc), 0
+	}
+
+	page := make([]models.Transaction, 0, pagination.PageSize)
+
+	if len(filter.Categories) == 0 && len(filter.Tags) == 0 && filter.MinAmount == nil && filter.MaxAmount == nil {
+		pageStart := min(skipEntries+skipMatched, len(entries))
+		pageEnd := min(pageStart+pagination.PageSize, len(entries))
+
+		for i := pageStart; i < pageEnd; i++ {
+			page = append(page, at(i))
+		}
+
+		return newTransactionsResponse(pagination, order, len(entries), page, pageEnd < len(entries))
+	}
+
+	total, hasMore := 0, false
+	for i := range entries {
+		transaction := at(i)
+		if !matchesFilter(transaction, filter) {
 			continue
 		}
+		total++
 
-		if len(categories) == 0 {
-			filteredTransactions = append(filteredTransactions, transaction)
+		if i < skipEntries || total <= skipMatched {
 			continue
 		}
 
-		// Фильтр по категориям
-		if len(categories) > 0 {
-			found := false
-			for _, category := range categories {
-				if transaction.Category == category {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
+		if len(page) < pagination.PageSize {
+			page = append(page, transaction)
+		} else {
+			hasMore = true
 		}
+	}
 
-		filteredTransactions = append(filteredTransactions, transaction)
+	return newTransactionsResponse(pagination, order, total, page, hasMore)
+}
+
+// newTransactionsResponse собирает ответ со страницей транзакций и курсором следующей страницы
+func newTransactionsResponse(
+	pagination models.TransactionsPagination,
+	order transactionsOrder,
+	total int,
+	page []models.Transaction,
+	hasMore bool,
+) *models.TransactionsResponse {
+	var nextCursor string
+	if hasMore && len(page) > 0 {
+		nextCursor = order.encodeCursor(page[len(page)-1])
 	}
 
-	// Сортируем по дате (новые сначала)
-	sort.Slice(filteredTransactions, func(i, j int) bool {
-		return filteredTransactions[i].Date.After(filteredTransactions[j].Date)
-	})
+	return &models.TransactionsResponse{
+		CurrentPage: pagination.Page,
+		TotalPages:  int(math.Ceil(float64(total) / float64(pagination.PageSize))),
+		TotalCount:  total,
+		NextCursor:  nextCursor,
+		Data:        page,
+	}
+}
 
-	// Применяем пагинацию
-	transactionsAmount := len(filteredTransactions)
-	totalPages := int(math.Ceil(float64(transactionsAmount) / float64(pageSize)))
+func (ts *TransactionsService) GetAllTransactions(ctx context.Context, fromDate, toDate time.Time) ([]models.Transaction, error) {
+	userID := models.ClaimsFromContext(ctx).ID
 
-	paginationStart := (page - 1) * pageSize
+	shard := ts.userShard(userID)
 
-	if paginationStart >= transactionsAmount {
-		return &models.TransactionsResponse{
-			CurrentPage: page,
-			TotalPages:  totalPages,
-			Data:        []models.Transaction{},
-		}, nil
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	// Выбираем диапазон дат по индексу, новые сначала
+	entries := shard.dateIndex.rangeOf(fromDate, toDate)
+
+	filteredTransactions := make([]models.Transaction, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		filteredTransactions = append(filteredTransactions, shard.transactions[entries[i].id])
 	}
 
-	paginationEnd := paginationStart + pageSize
-	if paginationEnd > transactionsAmount {
-		paginationEnd = transactionsAmount
+	return filteredTransactions, nil
+}
+
+// GetTags возвращает теги пользователя с количеством транзакций, начинающиеся с prefix
+func (ts *TransactionsService) GetTags(ctx context.Context, prefix string, limit int) ([]models.TagUsage, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	shard := ts.userShard(userID)
+
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	return shard.tagIndex.list(prefix, limit), nil
+}
+
+// SuggestCategories предлагает категории для новой транзакции по истории пользователя.
+// Сумма необязательна и только уточняет подсказку.
+func (ts *TransactionsService) SuggestCategories(ctx context.Context, title string, amount *float64, limit int) ([]models.CategorySuggestion, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	if strings.TrimSpace(title) == "" {
+		return nil, fmt.Errorf("%w: title is required", models.ErrBadRequest)
 	}
 
-	paginatedTransactions := filteredTransactions[paginationStart:paginationEnd]
+	names, err := ts.categories.GetCategoryNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
 
-	return &models.TransactionsResponse{
-		CurrentPage: page,
-		TotalPages:  totalPages,
-		Data:        paginatedTransactions,
-	}, nil
+	shard := ts.userShard(userID)
+
+	shard.mux.RLock()
+	suggestions := shard.suggestIndex.suggest(title, amount, limit)
+	shard.mux.RUnlock()
+
+	// Индекс хранит категории под исходными названиями, предлагаем их под новыми
+	for i := range suggestions {
+		suggestions[i].Category = categoryNames(names).display(suggestions[i].Category)
+	}
+
+	return suggestions, nil
 }
 
-func (ts *TransactionsService) GetAllTransactions(ctx context.Context, fromDate, toDate time.Time) ([]models.Transaction, error) {
+// StreamTransactions передает отфильтрованные транзакции пользователя в yield
+// пачками по streamBatchSize в порядке возрастания даты. Блокировка шарда
+// снимается между пачками, поэтому медленный получатель не задерживает запись.
+// Переименованные пользователем базовые категории передаются под новыми названиями.
+func (ts *TransactionsService) StreamTransactions(ctx context.Context, filter models.TransactionsFilter, yield func(batch []models.Transaction) error) error {
 	userID := models.ClaimsFromContext(ctx).ID
 
-	ts.mux.RLock()
-	defer ts.mux.RUnlock()
+	filter.Categories = ts.resolveCategories(ctx, filter.Categories)
 
-	userTransactions, exists := ts.transactions[userID]
-	if !exists {
-		ts.mux.RUnlock()
-		ts.mux.Lock()
-		ts.transactions[userID] = getInitialTransactions()
-		ts.mux.Unlock()
+	names, err := ts.categories.GetCategoryNames(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get categories: %w", err)
 	}
 
-	ts.mux.RLock()
+	shard := ts.userShard(userID)
 
-	// Конвертируем map в slice и применяем фильтры по датам
-	var filteredTransactions []models.Transaction
-	for _, transaction := range userTransactions {
-		// Фильтр по датам
-		if !fromDate.IsZero() && transaction.Date.Before(fromDate) {
-			continue
+	var (
+		last    models.Transaction
+		started bool
+	)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-		if !toDate.IsZero() && transaction.Date.After(toDate) {
-			continue
+
+		batch := make([]models.Transaction, 0, streamBatchSize)
+
+		shard.mux.RLock()
+		entries := shard.dateIndex.rangeOf(filter.FromDate, filter.ToDate)
+
+		// Продолжаем после последней переданной транзакции, даже если индекс изменился
+		position := 0
+		if started {
+			position = positionAfter(entries, last, false)
 		}
 
-		filteredTransactions = append(filteredTransactions, transaction)
-	}
+		for ; position < len(entries) && len(batch) < streamBatchSize; position++ {
+			if transaction := shard.transactions[entries[position].id]; matchesFilter(transaction, filter) {
+				batch = append(batch, transaction)
+			}
+		}
+		hasMore := position < len(entries)
+		shard.mux.RUnlock()
 
-	// Сортируем по дате (новые сначала)
-	sort.Slice(filteredTransactions, func(i, j int) bool {
-		return filteredTransactions[i].Date.After(filteredTransactions[j].Date)
-	})
+		if len(batch) > 0 {
+			last, started = batch[len(batch)-1], true
 
-	return filteredTransactions, nil
+			for i := range batch {
+				batch[i] = categoryNames(names).transaction(batch[i])
+			}
+			if err := yield(batch); err != nil {
+				return err
+			}
+		}
+
+		if !hasMore {
+			return nil
+		}
+	}
 }
 
 func (ts *TransactionsService) CreateTransaction(ctx context.Context, req models.CreateTransactionRequest) (*models.CreateTransactionResponse, error) {
 	userID := models.ClaimsFromContext(ctx).ID
 
+	transaction, err := ts.prepareTransaction(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	shard := ts.userShard(userID)
+
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	// Сохраняем транзакцию
+	shard.put(transaction)
+
+	return &models.CreateTransactionResponse{
+		ID: transaction.ID,
+	}, nil
+}
+
+// prepareTransaction применяет к запросу правила и категории пользователя и создает по нему транзакцию
+func (ts *TransactionsService) prepareTransaction(ctx context.Context, req models.CreateTransactionRequest) (models.Transaction, error) {
+	// Транзакцию без категории категоризируют правила пользователя
+	if req.Category == "" && len(req.Splits) == 0 {
+		applyRule(ctx, ts.rules, &req)
+	}
+
+	// Переименованная базовая категория сохраняется под исходным названием
+	req.Category = ts.categories.ResolveCategory(ctx, req.Category)
+	req.Splits = slices.Clone(req.Splits)
+	for i := range req.Splits {
+		req.Splits[i].Category = ts.categories.ResolveCategory(ctx, req.Splits[i].Category)
+	}
+
+	income, err := ts.categories.GetIncomeCategories(ctx)
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	return ts.newTransaction(req, income)
+}
+
+// normalizeFilter проверяет фильтр списка транзакций и приводит теги и категории к хранимому виду
+func (ts *TransactionsService) normalizeFilter(ctx context.Context, filter models.TransactionsFilter) (models.TransactionsFilter, error) {
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return filter, fmt.Errorf("%w: minAmount must not be greater than maxAmount", models.ErrBadRequest)
+	}
+
+	var err error
+	if filter.Tags, err = normalizeTags(filter.Tags); err != nil {
+		return filter, fmt.Errorf("%w: %w", models.ErrBadRequest, err)
+	}
+	if filter.TagsMatch != "" && filter.TagsMatch != models.TagsMatchAny && filter.TagsMatch != models.TagsMatchAll {
+		return filter, fmt.Errorf("%w: %w: %s, must be one of: any, all", models.ErrBadRequest, errUnknownTagsMatch, filter.TagsMatch)
+	}
+	filter.Categories = ts.resolveCategories(ctx, filter.Categories)
+
+	return filter, nil
+}
+
+// resolveCategories переводит категории фильтра в названия, под которыми они хранятся в транзакциях
+func (ts *TransactionsService) resolveCategories(ctx context.Context, categories []string) []string {
+	resolved := make([]string, len(categories))
+	for i, category := range categories {
+		resolved[i] = ts.categories.ResolveCategory(ctx, category)
+	}
+
+	return resolved
+}
+
+// ValidateTransaction проверяет запрос на создание транзакции, не сохраняя ее
+func (ts *TransactionsService) ValidateTransaction(req models.CreateTransactionRequest) error {
+	_, err := ts.newTransaction(req, nil)
+
+	return err
+}
+
+// newTransaction проверяет запрос и создает по нему транзакцию с новым ID.
+// Строки разбивки не могут относиться к категориям доходов income.
+func (ts *TransactionsService) newTransaction(req models.CreateTransactionRequest, income incomeCategories) (models.Transaction, error) {
 	// Парсим дату
 	date, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
-		return nil, fmt.Errorf("%w: invalid date format: %w", models.ErrBadRequest, err)
+		return models.Transaction{}, fmt.Errorf("%w: invalid date format: %w", models.ErrBadRequest, err)
 	}
 
 	if err := ts.validateRepeatString(req.RepeatTime); err != nil {
-		return nil, fmt.Errorf("%w: invalid repeat time format: %w", models.ErrBadRequest, err)
+		return models.Transaction{}, fmt.Errorf("%w: invalid repeat time format: %w", models.ErrBadRequest, err)
 	}
 
-	// Генерируем ID транзакции
-	transactionID := uuid.New().String()
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("%w: invalid tags: %w", models.ErrBadRequest, err)
+	}
+
+	splits, category, err := normalizeSplits(req.Splits, req.Amount, req.Category, income)
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("%w: invalid splits: %w", models.ErrBadRequest, err)
+	}
+
+	note := strings.TrimSpace(req.Note)
+	if len([]rune(note)) > models.MaxNoteLength {
+		return models.Transaction{}, fmt.Errorf("%w: note is longer than %d characters", models.ErrBadRequest, models.MaxNoteLength)
+	}
 
 	// Создаем транзакцию
 	transaction := models.Transaction{
-		ID:         transactionID,
+		ID:         uuid.New().String(),
 		Amount:     req.Amount,
 		Title:      req.Title,
-		Category:   req.Category,
+		Category:   category,
 		Date:       date,
 		RepeatTime: req.RepeatTime,
+		Tags:       tags,
+		Splits:     splits,
+		Note:       note,
 	}
 
 	// Обрабатываем повторяющиеся транзакции
 	if req.RepeatTime != "" {
-		nextAppearDate, err := ts.calculateNextAppearDate(date, req.RepeatTime)
+		nextAppearDate, err := calculateNextAppearDate(date, req.RepeatTime)
 		if err != nil {
-			return nil, fmt.Errorf("%w: invalid repeat time format: %w", models.ErrBadRequest, err)
+			return models.Transaction{}, fmt.Errorf("%w: invalid repeat time format: %w", models.ErrBadRequest, err)
 		}
 		transaction.NextAppearDate = nextAppearDate
 	}
 
-	ts.mux.Lock()
-	defer ts.mux.Unlock()
+	return transaction, nil
+}
 
-	// Инициализируем map для пользователя если не существует
-	if ts.transactions[userID] == nil {
-		ts.transactions[userID] = getInitialTransactions()
-	}
+// DeleteTransaction переносит транзакцию в корзину, вложения при этом сохраняются
+func (ts *TransactionsService) DeleteTransaction(ctx context.Context, id string) error {
+	userID := models.ClaimsFromContext(ctx).ID
 
-	// Сохраняем транзакцию
-	ts.transactions[userID][transactionID] = transaction
+	shard := ts.userShard(userID)
 
-	return &models.CreateTransactionResponse{
-		ID: transactionID,
-	}, nil
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	shard.moveToTrash(id, time.Now().UTC())
+	return nil
 }
 
-func (ts *TransactionsService) DeleteTransaction(ctx context.Context, id string) error {
-	userID := models.ClaimsFromContext(ctx).ID
+// matchesFilter проверяет транзакцию на соответствие фильтрам по датам, категориям, сумме и тегам
+func matchesFilter(transaction models.Transaction, filter models.TransactionsFilter) bool {
+	// Фильтр по датам
+	if !filter.FromDate.IsZero() && transaction.Date.Before(filter.FromDate) {
+		return false
+	}
+	if !filter.ToDate.IsZero() && transaction.Date.After(filter.ToDate) {
+		return false
+	}
 
-	ts.mux.Lock()
-	defer ts.mux.Unlock()
+	// Фильтр по сумме
+	if filter.MinAmount != nil && transaction.Amount < *filter.MinAmount {
+		return false
+	}
+	if filter.MaxAmount != nil && transaction.Amount > *filter.MaxAmount {
+		return false
+	}
 
-	userTransactions, exists := ts.transactions[userID]
-	if !exists {
-		return nil
+	// Фильтр по категориям, разбитая транзакция подходит по категории любой строки
+	if len(filter.Categories) > 0 && !slices.ContainsFunc(categoryLines(transaction), func(line models.TransactionSplit) bool {
+		return slices.Contains(filter.Categories, line.Category)
+	}) {
+		return false
 	}
 
-	delete(userTransactions, id)
-	return nil
+	return matchesTags(transaction, filter.Tags, filter.TagsMatch)
 }
 
 // calculateNextAppearDate вычисляет следующую дату появления для повторяющихся транзакций
-func (ts *TransactionsService) calculateNextAppearDate(now time.Time, repeatTime string) (time.Time, error) {
+func calculateNextAppearDate(now time.Time, repeatTime string) (time.Time, error) {
 	repeatTimes := strings.Split(repeatTime, ",")
 	nextAppearDate := now.AddDate(1, 0, 0)
 
@@ -317,28 +601,183 @@ func convertToWeekDay(day string) (time.Weekday, error) {
 
 // GetBackupData возвращает данные для бэкапа
 func (ts *TransactionsService) GetBackupData() interface{} {
-	ts.mux.RLock()
-	defer ts.mux.RUnlock()
-
 	// Создаем копию данных для бэкапа
 	backupData := make(map[string]map[string]models.Transaction)
-	for userID, transactions := range ts.transactions {
-		backupTransactions := make(map[string]models.Transaction)
-		for transactionID, transaction := range transactions {
-			backupTransaction := models.Transaction{
-				ID:             transaction.ID,
-				Amount:         transaction.Amount,
-				Title:          transaction.Title,
-				Category:       transaction.Category,
-				Date:           transaction.Date,
-				NextAppearDate: transaction.NextAppearDate,
+	for userID, shard := range ts.allShards() {
+		shard.mux.RLock()
+		backupData[userID] = backupTransactions(shard)
+		shard.mux.RUnlock()
+	}
+
+	return backupData
+}
+
+// backupTransactions копирует транзакции шарда вместе с корзиной в формате бэкапа.
+// Вызывается под блокировкой шарда.
+func backupTransactions(shard *userShard) map[string]models.Transaction {
+	backupTransactions := make(map[string]models.Transaction, len(shard.transactions)+len(shard.trash))
+	for transactionID, transaction := range shard.allTransactions() {
+		backupTransaction := models.Transaction{
+			ID:             transaction.ID,
+			Amount:         transaction.Amount,
+			Title:          transaction.Title,
+			Category:       transaction.Category,
+			Date:           transaction.Date,
+			NextAppearDate: transaction.NextAppearDate,
+			Tags:           slices.Clone(transaction.Tags),
+			Splits:         slices.Clone(transaction.Splits),
+			Note:           transaction.Note,
+			Attachments:    slices.Clone(transaction.Attachments),
+			Receipt:        transaction.Receipt,
+			DeletedAt:      transaction.DeletedAt,
+		}
+		backupTransactions[transactionID] = backupTransaction
+	}
+
+	return backupTransactions
+}
+
+// ExportUserTransactions возвращает транзакции пользователя в формате бэкапа
+// и правила повторения, которые в бэкап не попадают
+func (ts *TransactionsService) ExportUserTransactions(ctx context.Context) (map[string]models.Transaction, map[string]string) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	shard := ts.userShard(userID)
+
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	recurrence := make(map[string]string)
+	for transactionID, transaction := range shard.allTransactions() {
+		if transaction.RepeatTime != "" {
+			recurrence[transactionID] = transaction.RepeatTime
+		}
+	}
+
+	return backupTransactions(shard), recurrence
+}
+
+// ImportUserTransactions загружает транзакции пользователя из архива. Все транзакции
+// проверяются до изменения данных; при replace существующие транзакции и корзина удаляются.
+// Транзакции с датой удаления попадают в корзину. Вложения, файлов которых нет в хранилище
+// (например, архив выгружен с другого сервера), отбрасываются.
+// Возвращает число загруженных транзакций и отброшенных вложений.
+func (ts *TransactionsService) ImportUserTransactions(
+	ctx context.Context,
+	transactions map[string]models.Transaction,
+	recurrence map[string]string,
+	replace bool,
+) (int, int, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	income, err := ts.categories.GetIncomeCategories(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	missingAttachments := 0
+
+	imported := make([]models.Transaction, 0, len(transactions))
+	for transactionID, transaction := range transactions {
+		if transaction.ID == "" {
+			transaction.ID = transactionID
+		}
+		if transaction.ID != transactionID {
+			return 0, 0, fmt.Errorf("%w: transaction id %s does not match its key %s", models.ErrBadRequest, transaction.ID, transactionID)
+		}
+		if transaction.Date.IsZero() {
+			return 0, 0, fmt.Errorf("%w: transaction %s has no date", models.ErrBadRequest, transactionID)
+		}
+
+		tags, err := normalizeTags(transaction.Tags)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: invalid tags of transaction %s: %w", models.ErrBadRequest, transactionID, err)
+		}
+		transaction.Tags = tags
+
+		splits, category, err := normalizeSplits(transaction.Splits, transaction.Amount, transaction.Category, income)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: invalid splits of transaction %s: %w", models.ErrBadRequest, transactionID, err)
+		}
+		transaction.Splits, transaction.Category = splits, category
+
+		attachments := make([]models.Attachment, 0, len(transaction.Attachments))
+		for _, attachment := range transaction.Attachments {
+			if err := validateAttachmentID(attachment.ID); err != nil {
+				return 0, 0, fmt.Errorf("%w: invalid attachment of transaction %s: %w", models.ErrBadRequest, transactionID, err)
+			}
+			if !ts.attachments.Exists(userID, attachment) {
+				missingAttachments++
+				continue
 			}
-			backupTransactions[transactionID] = backupTransaction
+			attachments = append(attachments, attachment)
 		}
-		backupData[userID] = backupTransactions
+		transaction.Attachments = nil
+		if len(attachments) > 0 {
+			transaction.Attachments = attachments
+		}
+
+		transaction.RepeatTime = recurrence[transactionID]
+		if err := ts.validateRepeatString(transaction.RepeatTime); err != nil {
+			return 0, 0, fmt.Errorf("%w: invalid repeat time of transaction %s: %w", models.ErrBadRequest, transactionID, err)
+		}
+		if transaction.RepeatTime != "" && transaction.NextAppearDate.IsZero() {
+			nextAppearDate, err := calculateNextAppearDate(transaction.Date, transaction.RepeatTime)
+			if err != nil {
+				return 0, 0, fmt.Errorf("%w: invalid repeat time of transaction %s: %w", models.ErrBadRequest, transactionID, err)
+			}
+			transaction.NextAppearDate = nextAppearDate
+		}
+
+		imported = append(imported, transaction)
 	}
 
-	return backupData
+	for transactionID := range recurrence {
+		if _, exists := transactions[transactionID]; !exists {
+			return 0, 0, fmt.Errorf("%w: repeat time refers to unknown transaction %s", models.ErrBadRequest, transactionID)
+		}
+	}
+
+	shard := ts.userShard(userID)
+
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	// Вложения замененных и удаленных транзакций, на которые больше никто не ссылается, удаляются
+	var previousAttachments []models.Attachment
+	for _, transaction := range shard.allTransactions() {
+		previousAttachments = append(previousAttachments, transaction.Attachments...)
+	}
+
+	if replace {
+		for transactionID := range shard.transactions {
+			shard.remove(transactionID)
+		}
+		clear(shard.trash)
+	}
+
+	// Транзакция с одним ID не может быть одновременно в списке и в корзине
+	for _, transaction := range imported {
+		shard.remove(transaction.ID)
+		delete(shard.trash, transaction.ID)
+		if transaction.DeletedAt != nil {
+			shard.trash[transaction.ID] = transaction
+			continue
+		}
+		shard.put(transaction)
+	}
+
+	ts.attachments.Remove(userID, orphanedAttachments(shard, previousAttachments))
+
+	return len(imported), missingAttachments, nil
+}
+
+// DeleteUserData удаляет все транзакции пользователя вместе с индексами
+func (ts *TransactionsService) DeleteUserData(userID string) {
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+
+	delete(ts.shards, userID)
 }
 
 // GetBackupFileName возвращает имя файла для бэкапа
@@ -350,48 +789,56 @@ func (ts *TransactionsService) GetBackupFileName() string {
 func (ts *TransactionsService) ProcessAllRecurringTransactions() error {
 	today := time.Now().Truncate(24 * time.Hour)
 
-	ts.mux.Lock()
-	defer ts.mux.Unlock()
+	// Обрабатываем всех пользователей, блокируя каждого по отдельности
+	for _, shard := range ts.allShards() {
+		processRecurringTransactions(shard, today)
+	}
 
-	// Обрабатываем всех пользователей
-	for _, userTransactions := range ts.transactions {
-		// Находим транзакции, которые должны повториться сегодня
-		var transactionsToProcess []models.Transaction
-		for _, transaction := range userTransactions {
-			if !transaction.NextAppearDate.IsZero() &&
-				transaction.NextAppearDate.Truncate(24*time.Hour).Equal(today) &&
-				transaction.RepeatTime != "" {
-				transactionsToProcess = append(transactionsToProcess, transaction)
-			}
+	return nil
+}
+
+// processRecurringTransactions создает сегодняшние повторения транзакций пользователя
+func processRecurringTransactions(shard *userShard, today time.Time) {
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	// Находим транзакции, которые должны повториться сегодня
+	var transactionsToProcess []models.Transaction
+	for _, transaction := range shard.transactions {
+		if !transaction.NextAppearDate.IsZero() &&
+			transaction.NextAppearDate.Truncate(24*time.Hour).Equal(today) &&
+			transaction.RepeatTime != "" {
+			transactionsToProcess = append(transactionsToProcess, transaction)
 		}
+	}
 
-		// Создаем новые транзакции для повторения
-		for _, originalTransaction := range transactionsToProcess {
-			// Создаем новую транзакцию на основе оригинальной
-			newTransaction := models.Transaction{
-				ID:         uuid.New().String(),
-				Amount:     originalTransaction.Amount,
-				Title:      originalTransaction.Title,
-				Category:   originalTransaction.Category,
-				Date:       today,
-				RepeatTime: originalTransaction.RepeatTime,
-			}
+	// Создаем новые транзакции для повторения
+	for _, originalTransaction := range transactionsToProcess {
+		// Создаем новую транзакцию на основе оригинальной
+		newTransaction := models.Transaction{
+			ID:         uuid.New().String(),
+			Amount:     originalTransaction.Amount,
+			Title:      originalTransaction.Title,
+			Category:   originalTransaction.Category,
+			Date:       today,
+			RepeatTime: originalTransaction.RepeatTime,
+			Tags:       originalTransaction.Tags,
+			Splits:     originalTransaction.Splits,
+			Note:       originalTransaction.Note,
+		}
 
-			// Вычисляем следующую дату появления
-			nextAppearDate, err := ts.calculateNextAppearDate(today, originalTransaction.RepeatTime)
-			if err == nil {
-				newTransaction.NextAppearDate = nextAppearDate
-			}
+		// Вычисляем следующую дату появления
+		nextAppearDate, err := calculateNextAppearDate(today, originalTransaction.RepeatTime)
+		if err == nil {
+			newTransaction.NextAppearDate = nextAppearDate
+		}
 
-			// Добавляем новую транзакцию
-			userTransactions[newTransaction.ID] = newTransaction
+		// Добавляем новую транзакцию
+		shard.put(newTransaction)
 
-			originalTransaction.RepeatTime = ""
-			userTransactions[originalTransaction.ID] = originalTransaction
-		}
+		originalTransaction.RepeatTime = ""
+		shard.put(originalTransaction)
 	}
-
-	return nil
 }
 
 func getInitialTransactions() map[string]models.Transaction {
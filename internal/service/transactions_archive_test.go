@@ -0,0 +1,42 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"spendings-backend/internal/models"
+)
+
+func TestImportUserTransactionsMissingAttachments(t *testing.T) {
+	ts := newStressTransactionsService(t)
+	ctx := userContext("archive")
+
+	// Файл одного вложения есть в хранилище пользователя, второе выгружено с другого сервера
+	stored, err := ts.attachments.Save("archive", "receipt.pdf", strings.NewReader("%PDF-1.4\n"))
+	require.NoError(t, err)
+	missing := models.Attachment{ID: uuid.New().String(), FileName: "other.pdf"}
+
+	imported, missingAttachments, err := ts.ImportUserTransactions(ctx, map[string]models.Transaction{
+		"1": {Title: "Покупка", Category: "Еда", Amount: 100, Date: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+			Attachments: []models.Attachment{stored, missing}},
+		"2": {Title: "Такси", Category: "Транспорт", Amount: 300, Date: time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC),
+			Attachments: []models.Attachment{missing}},
+	}, nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, imported)
+	assert.Equal(t, 2, missingAttachments)
+
+	transactions, err := ts.GetAllTransactions(ctx, time.Time{}, time.Time{})
+	require.NoError(t, err)
+
+	attachments := make(map[string][]models.Attachment, len(transactions))
+	for _, transaction := range transactions {
+		attachments[transaction.ID] = transaction.Attachments
+	}
+	assert.Equal(t, map[string][]models.Attachment{"1": {stored}, "2": nil}, attachments)
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errUnknownBatchOperation = errors.New("unknown batch operation, must be one of: create, update, delete, recategorize")
+	errBatchSelector         = errors.New("exactly one of ids and filter must be set")
+	errBatchTooLarge         = errors.New("batch is too large")
+)
+
+// batchOperation операция пакета, проверенная и подготовленная до блокировки шарда
+type batchOperation struct {
+	index  int
+	op     string
+	ids    []string
+	filter *models.TransactionsFilter
+	// transaction и createErr - результат подготовки транзакции для create
+	transaction models.Transaction
+	createErr   error
+	update      models.TransactionUpdate
+	category    string
+}
+
+// BatchTransactions применяет операции пакета по порядку под одной блокировкой шарда,
+// каждая операция видит результат предыдущих. Если хотя бы один элемент не выполнен,
+// все изменения пакета откатываются, а в результате Applied равен false.
+func (ts *TransactionsService) BatchTransactions(ctx context.Context, req models.BatchRequest) (*models.BatchResult, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	operations, err := ts.prepareBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	shard := ts.userShard(userID)
+
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	batch := &batchApplier{
+		ts:        ts,
+		shard:     shard,
+		now:       time.Now().UTC(),
+		originals: make(map[string]*models.Transaction),
+		results:   make([]models.BatchItemResult, 0),
+	}
+	for _, operation := range operations {
+		batch.apply(operation)
+		// Фильтр может выбрать сколько угодно транзакций, поэтому размер проверяется по ходу
+		if len(batch.originals) > models.MaxBatchItems {
+			batch.rollback()
+			return nil, fmt.Errorf("%w: %w: more than %d transactions affected", models.ErrBadRequest, errBatchTooLarge, models.MaxBatchItems)
+		}
+	}
+
+	result := &models.BatchResult{
+		Failed:  batch.failed,
+		Results: batch.results,
+	}
+	if batch.failed > 0 {
+		batch.rollback()
+		return result, nil
+	}
+	result.Applied = true
+
+	return result, nil
+}
+
+// prepareBatch проверяет структуру пакета и готовит операции: транзакции для create,
+// фильтры и категории. Ошибки в данных создаваемых транзакций не прерывают пакет,
+// а попадают в результат элемента.
+func (ts *TransactionsService) prepareBatch(ctx context.Context, req models.BatchRequest) ([]batchOperation, error) {
+	if len(req.Operations) == 0 {
+		return nil, fmt.Errorf("%w: batch has no operations", models.ErrBadRequest)
+	}
+	if len(req.Operations) > models.MaxBatchOperations {
+		return nil, fmt.Errorf("%w: %w: limit is %d operations", models.ErrBadRequest, errBatchTooLarge, models.MaxBatchOperations)
+	}
+
+	operations := make([]batchOperation, 0, len(req.Operations))
+	for i, op := range req.Operations {
+		operation, err := ts.prepareBatchOperation(ctx, i, op)
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %w", models.ErrBadRequest, i, err)
+		}
+		operations = append(operations, operation)
+	}
+
+	return operations, nil
+}
+
+func (ts *TransactionsService) prepareBatchOperation(ctx context.Context, index int, op models.BatchOperation) (batchOperation, error) {
+	operation := batchOperation{index: index, op: op.Op}
+
+	if op.Op == models.BatchCreate {
+		if op.Transaction == nil {
+			return batchOperation{}, errors.New("transaction must be set for create")
+		}
+		if len(op.IDs) > 0 || op.Filter != nil {
+			return batchOperation{}, errors.New("ids and filter are not allowed for create")
+		}
+		operation.transaction, operation.createErr = ts.prepareTransaction(ctx, *op.Transaction)
+		return operation, nil
+	}
+
+	switch op.Op {
+	case models.BatchUpdate:
+		if op.Update == nil {
+			return batchOperation{}, errors.New("update must be set for update")
+		}
+		operation.update = *op.Update
+		if operation.update.Category != nil {
+			category := ts.categories.ResolveCategory(ctx, *operation.update.Category)
+			operation.update.Category = &category
+		}
+	case models.BatchDelete:
+	case models.BatchRecategorize:
+		if op.Category == "" {
+			return batchOperation{}, errors.New("category must be set for recategorize")
+		}
+		operation.category = ts.categories.ResolveCategory(ctx, op.Category)
+	default:
+		return batchOperation{}, fmt.Errorf("%w: %s", errUnknownBatchOperation, op.Op)
+	}
+
+	if (len(op.IDs) > 0) == (op.Filter != nil) {
+		return batchOperation{}, errBatchSelector
+	}
+
+	// Повторный ID в одной операции не должен давать ошибку "не найдена" после удаления
+	for _, id := range op.IDs {
+		if !slices.Contains(operation.ids, id) {
+			operation.ids = append(operation.ids, id)
+		}
+	}
+
+	if op.Filter != nil {
+		filter, err := ts.batchFilter(ctx, *op.Filter)
+		if err != nil {
+			return batchOperation{}, err
+		}
+		operation.filter = &filter
+	}
+
+	return operation, nil
+}
+
+// batchFilter переводит фильтр пакета в фильтр списка транзакций
+func (ts *TransactionsService) batchFilter(ctx context.Context, batchFilter models.BatchFilter) (models.TransactionsFilter, error) {
+	filter := models.TransactionsFilter{
+		Categories: batchFilter.Categories,
+		Query:      batchFilter.Query,
+		MinAmount:  batchFilter.MinAmount,
+		MaxAmount:  batchFilter.MaxAmount,
+		Tags:       batchFilter.Tags,
+		TagsMatch:  batchFilter.TagsMatch,
+	}
+
+	var err error
+	if batchFilter.From != "" {
+		if filter.FromDate, err = time.Parse("2006-01-02", batchFilter.From); err != nil {
+			return filter, fmt.Errorf("invalid from date format: %w", err)
+		}
+	}
+	if batchFilter.To != "" {
+		if filter.ToDate, err = time.Parse("2006-01-02", batchFilter.To); err != nil {
+			return filter, fmt.Errorf("invalid to date format: %w", err)
+		}
+	}
+
+	return ts.normalizeFilter(ctx, filter)
+}
+
+// batchApplier применяет операции пакета к шарду и запоминает исходные транзакции для отката.
+// Используется под блокировкой шарда на запись.
+type batchApplier struct {
+	ts    *TransactionsService
+	shard *userShard
+	// now время удаления транзакций пакета в корзину
+	now time.Time
+	// originals транзакции до пакета, nil - транзакция создана пакетом
+	originals map[string]*models.Transaction
+	results   []models.BatchItemResult
+	failed    int
+}
+
+func (ba *batchApplier) apply(operation batchOperation) {
+	if operation.op == models.BatchCreate {
+		if operation.createErr != nil {
+			ba.fail(operation, "", operation.createErr)
+			return
+		}
+		ba.remember(operation.transaction.ID)
+		ba.shard.put(operation.transaction)
+		ba.succeed(operation, operation.transaction.ID, models.BatchStatusCreated)
+		return
+	}
+
+	for _, transaction := range ba.selected(operation) {
+		switch operation.op {
+		case models.BatchUpdate:
+			updated, err := ba.ts.updatedTransaction(transaction, operation.update)
+			if err != nil {
+				ba.fail(operation, transaction.ID, err)
+				continue
+			}
+			ba.remember(transaction.ID)
+			ba.shard.put(updated)
+			ba.succeed(operation, transaction.ID, models.BatchStatusUpdated)
+		case models.BatchDelete:
+			ba.remember(transaction.ID)
+			ba.shard.moveToTrash(transaction.ID, ba.now)
+			ba.succeed(operation, transaction.ID, models.BatchStatusDeleted)
+		case models.BatchRecategorize:
+			ba.remember(transaction.ID)
+			transaction.Category = operation.category
+			transaction.Splits = nil
+			ba.shard.put(transaction)
+			ba.succeed(operation, transaction.ID, models.BatchStatusRecategorized)
+		}
+	}
+}
+
+// selected возвращает транзакции операции: по списку ID в порядке запроса или по фильтру
+// в порядке дат. Не найденные ID сразу отмечаются как невыполненные.
+func (ba *batchApplier) selected(operation batchOperation) []models.Transaction {
+	if operation.filter != nil {
+		transactions := ba.shard.selectTransactions(*operation.filter)
+		slices.SortFunc(transactions, func(a, b models.Transaction) int {
+			if c := a.Date.Compare(b.Date); c != 0 {
+				return c
+			}
+			return strings.Compare(a.ID, b.ID)
+		})
+		return transactions
+	}
+
+	transactions := make([]models.Transaction, 0, len(operation.ids))
+	for _, id := range operation.ids {
+		transaction, exists := ba.shard.transactions[id]
+		if !exists {
+			ba.fail(operation, id, fmt.Errorf("%w: transaction %s not found", models.ErrNotFound, id))
+			continue
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions
+}
+
+// remember сохраняет транзакцию в том виде, в каком она была до пакета
+func (ba *batchApplier) remember(id string) {
+	if _, exists := ba.originals[id]; exists {
+		return
+	}
+
+	if transaction, exists := ba.shard.transactions[id]; exists {
+		ba.originals[id] = &transaction
+	} else {
+		ba.originals[id] = nil
+	}
+}
+
+// rollback возвращает шард к состоянию до пакета. Сначала удаляются все затронутые
+// транзакции, чтобы индекс чеков не зависел от порядка восстановления. До пакета
+// затронутых транзакций в корзине не было, поэтому их записи в корзине тоже удаляются.
+func (ba *batchApplier) rollback() {
+	for id := range ba.originals {
+		ba.shard.remove(id)
+		delete(ba.shard.trash, id)
+	}
+	for _, original := range ba.originals {
+		if original != nil {
+			ba.shard.put(*original)
+		}
+	}
+}
+
+func (ba *batchApplier) succeed(operation batchOperation, id, status string) {
+	ba.results = append(ba.results, models.BatchItemResult{
+		Operation: operation.index,
+		ID:        id,
+		Status:    status,
+	})
+}
+
+func (ba *batchApplier) fail(operation batchOperation, id string, err error) {
+	ba.failed++
+	ba.results = append(ba.results, models.BatchItemResult{
+		Operation: operation.index,
+		ID:        id,
+		Status:    models.BatchStatusFailed,
+		Error:     err.Error(),
+	})
+}
+
+// updatedTransaction применяет к транзакции изменяемые поля и проверяет результат так же,
+// как при создании. Смена категории снимает разбивку. ID, вложения и чек сохраняются,
+// дата следующего повтора пересчитывается только при смене даты.
+func (ts *TransactionsService) updatedTransaction(transaction models.Transaction, update models.TransactionUpdate) (models.Transaction, error) {
+	req := models.CreateTransactionRequest{
+		Amount:     transaction.Amount,
+		Title:      transaction.Title,
+		Category:   transaction.Category,
+		Date:       transaction.Date.Format("2006-01-02"),
+		RepeatTime: transaction.RepeatTime,
+		Tags:       transaction.Tags,
+		Splits:     transaction.Splits,
+		Note:       transaction.Note,
+	}
+	if update.Amount != nil {
+		req.Amount = *update.Amount
+	}
+	if update.Title != nil {
+		req.Title = *update.Title
+	}
+	if update.Category != nil {
+		req.Category = *update.Category
+		req.Splits = nil
+	}
+	if update.Date != nil {
+		req.Date = *update.Date
+	}
+	if update.Tags != nil {
+		req.Tags = *update.Tags
+	}
+	if update.Note != nil {
+		req.Note = *update.Note
+	}
+
+	// Обновление не добавляет строки разбивки: прежние уже проверены, новая категория их сбрасывает
+	updated, err := ts.newTransaction(req, nil)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	updated.ID = transaction.ID
+	updated.Attachments = transaction.Attachments
+	updated.Receipt = transaction.Receipt
+	if updated.Date.Equal(transaction.Date) {
+		updated.NextAppearDate = transaction.NextAppearDate
+	}
+
+	return updated, nil
+}
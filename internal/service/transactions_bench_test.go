@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"spendings-backend/internal/models"
+)
+
+// benchmarkTransactionsCount примерно три года активного пользователя: около 18 транзакций в день
+const benchmarkTransactionsCount = 20000
+
+var benchmarkTitles = []string{
+	"Пятерочка", "Перекресток", "ВкусВилл", "Кофе с собой", "Такси до работы",
+	"Метро", "Аптека", "Кино", "Зарплата", "Аренда квартиры",
+	"Мобильная связь", "Интернет", "Спортзал", "Ресторан", "Доставка еды",
+}
+
+var benchmarkCategories = []string{"Еда", "Транспорт", "Развлечения", "Здоровье", "Прочее"}
+
+func userContext(userID string) context.Context {
+	return context.WithValue(context.Background(), models.ContextClaimsKey{}, &models.AuthTokenClaims{
+		RegisteredClaims: &jwt.RegisteredClaims{ID: userID},
+	})
+}
+
+// newBenchmarkTransactionsService создает сервис с одним пользователем и count транзакциями за последние три года
+func newBenchmarkTransactionsService(count int) (*TransactionsService, context.Context) {
+	random := rand.New(rand.NewPCG(1, 2))
+	today := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	transactions := make(map[string]models.Transaction, count)
+	for i := range count {
+		id := fmt.Sprintf("%08d", i)
+		transactions[id] = models.Transaction{
+			ID:       id,
+			Title:    benchmarkTitles[random.IntN(len(benchmarkTitles))],
+			Category: benchmarkCategories[random.IntN(len(benchmarkCategories))],
+			Amount:   float64(random.IntN(500000)) / 100,
+			Date:     today.AddDate(0, 0, -random.IntN(3*365)),
+		}
+	}
+
+	categories := NewCategoriesService(nil, models.GetDefaultBaseCategories())
+	ts := NewTransactionsService(map[string]map[string]models.Transaction{"bench": transactions}, categories, NewRulesService(nil), nil, time.Hour)
+
+	return ts, userContext("bench")
+}
+
+// linearTransactions выбирает транзакции перебором всех транзакций пользователя,
+// как до появления индексов, и сортирует их в порядке order
+func linearTransactions(ctx context.Context, ts *TransactionsService, filter models.TransactionsFilter, order transactionsOrder) []models.Transaction {
+	shard := ts.userShard(models.ClaimsFromContext(ctx).ID)
+
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	query := strings.ToLower(strings.TrimSpace(filter.Query))
+
+	var selected []models.Transaction
+	for _, transaction := range shard.transactions {
+		if query != "" && !strings.Contains(strings.ToLower(searchableText(transaction)), query) {
+			continue
+		}
+		if matchesFilter(transaction, filter) {
+			selected = append(selected, transaction)
+		}
+	}
+	slices.SortFunc(selected, order.compare)
+
+	return selected
+}
+
+// linearPage возвращает страницу транзакций, отсортированных по дате, перебором всех транзакций
+func linearPage(ctx context.Context, ts *TransactionsService, filter models.TransactionsFilter, page, pageSize int) []models.Transaction {
+	selected := linearTransactions(ctx, ts, filter, transactionsOrder{sortBy: models.SortByDate, desc: true})
+
+	start := min((page-1)*pageSize, len(selected))
+	end := min(start+pageSize, len(selected))
+
+	return selected[start:end]
+}
+
+// assertSameIDs проверяет, что индекс и перебор выбрали одни и те же транзакции в одном порядке
+func assertSameIDs(b *testing.B, indexed, linear []models.Transaction) {
+	b.Helper()
+
+	if len(indexed) != len(linear) {
+		b.Fatalf("index returned %d transactions, linear scan %d", len(indexed), len(linear))
+	}
+	for i := range indexed {
+		if indexed[i].ID != linear[i].ID {
+			b.Fatalf("transaction %d: index returned %s, linear scan %s", i, indexed[i].ID, linear[i].ID)
+		}
+	}
+}
+
+func BenchmarkGetAllTransactionsMonth(b *testing.B) {
+	ts, ctx := newBenchmarkTransactionsService(benchmarkTransactionsCount)
+	from := time.Date(2026, time.August, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.August, 31, 0, 0, 0, 0, time.UTC)
+
+	indexed, _ := ts.GetAllTransactions(ctx, from, to)
+	linear := linearTransactions(ctx, ts, models.TransactionsFilter{FromDate: from, ToDate: to}, transactionsOrder{sortBy: models.SortByDate, desc: true})
+	assertSameIDs(b, indexed, linear)
+
+	b.Run("index", func(b *testing.B) {
+		for b.Loop() {
+			_, _ = ts.GetAllTransactions(ctx, from, to)
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for b.Loop() {
+			linearTransactions(ctx, ts, models.TransactionsFilter{FromDate: from, ToDate: to}, transactionsOrder{sortBy: models.SortByDate, desc: true})
+		}
+	})
+}
+
+func BenchmarkGetTransactionsPage(b *testing.B) {
+	ts, ctx := newBenchmarkTransactionsService(benchmarkTransactionsCount)
+
+	cases := []struct {
+		name   string
+		filter models.TransactionsFilter
+		page   int
+	}{
+		{name: "first", page: 1},
+		{name: "deep", page: 200},
+		{name: "category", filter: models.TransactionsFilter{Categories: []string{"Транспорт"}}, page: 1},
+	}
+
+	for _, tc := range cases {
+		pagination := models.TransactionsPagination{Page: tc.page, PageSize: 20}
+
+		response, err := ts.GetTransactions(ctx, tc.filter, pagination)
+		if err != nil {
+			b.Fatal(err)
+		}
+		assertSameIDs(b, response.Data, linearPage(ctx, ts, tc.filter, tc.page, 20))
+
+		b.Run(tc.name+"/index", func(b *testing.B) {
+			for b.Loop() {
+				_, _ = ts.GetTransactions(ctx, tc.filter, pagination)
+			}
+		})
+		b.Run(tc.name+"/linear", func(b *testing.B) {
+			for b.Loop() {
+				linearPage(ctx, ts, tc.filter, tc.page, 20)
+			}
+		})
+	}
+}
+
+func BenchmarkGetTransactionsCursor(b *testing.B) {
+	ts, ctx := newBenchmarkTransactionsService(benchmarkTransactionsCount)
+
+	// Курсор после 4000 транзакций: следующая страница ищется бинарным поиском по индексу дат
+	response, err := ts.GetTransactions(ctx, models.TransactionsFilter{}, models.TransactionsPagination{Page: 1, PageSize: 4000})
+	if err != nil {
+		b.Fatal(err)
+	}
+	pagination := models.TransactionsPagination{Page: 1, PageSize: 20, Cursor: response.NextCursor}
+
+	next, err := ts.GetTransactions(ctx, models.TransactionsFilter{}, pagination)
+	if err != nil {
+		b.Fatal(err)
+	}
+	assertSameIDs(b, next.Data, linearPage(ctx, ts, models.TransactionsFilter{}, 201, 20))
+
+	b.Run("index", func(b *testing.B) {
+		for b.Loop() {
+			_, _ = ts.GetTransactions(ctx, models.TransactionsFilter{}, pagination)
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for b.Loop() {
+			linearPage(ctx, ts, models.TransactionsFilter{}, 201, 20)
+		}
+	})
+}
+
+func BenchmarkSearchTransactions(b *testing.B) {
+	ts, ctx := newBenchmarkTransactionsService(benchmarkTransactionsCount)
+	filter := models.TransactionsFilter{Query: "кофе"}
+	pagination := models.TransactionsPagination{Page: 1, PageSize: 20}
+
+	response, err := ts.GetTransactions(ctx, filter, pagination)
+	if err != nil {
+		b.Fatal(err)
+	}
+	assertSameIDs(b, response.Data, linearPage(ctx, ts, filter, 1, 20))
+
+	b.Run("index", func(b *testing.B) {
+		for b.Loop() {
+			_, _ = ts.GetTransactions(ctx, filter, pagination)
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for b.Loop() {
+			linearPage(ctx, ts, filter, 1, 20)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"spendings-backend/internal/models"
+)
+
+var (
+	errInvalidSortField = errors.New("invalid sort field")
+	errInvalidSortOrder = errors.New("invalid sort order")
+	errInvalidCursor    = errors.New("invalid cursor")
+)
+
+// transactionsCursor позиция в отсортированном списке транзакций.
+// Хранит ключ сортировки последнего отданного элемента, поэтому вставки
+// и удаления между запросами не сдвигают следующую страницу.
+type transactionsCursor struct {
+	SortBy string    `json:"s"`
+	Order  string    `json:"o"`
+	Date   time.Time `json:"d"`
+	Amount float64   `json:"a"`
+	Title  string    `json:"t"`
+	ID     string    `json:"i"`
+}
+
+// transactionsOrder задает порядок сортировки транзакций
+type transactionsOrder struct {
+	sortBy string
+	desc   bool
+}
+
+func newTransactionsOrder(sortBy, order string) (transactionsOrder, error) {
+	if sortBy == "" {
+		sortBy = models.SortByDate
+	}
+
+	switch sortBy {
+	case models.SortByDate, models.SortByAmount, models.SortByTitle:
+	default:
+		return transactionsOrder{}, fmt.Errorf("%w: %s, must be one of: date, amount, title", errInvalidSortField, sortBy)
+	}
+
+	switch order {
+	case "":
+		// По умолчанию даты - новые сначала, остальные поля - по возрастанию
+		return transactionsOrder{sortBy: sortBy, desc: sortBy == models.SortByDate}, nil
+	case models.SortOrderAsc:
+		return transactionsOrder{sortBy: sortBy}, nil
+	case models.SortOrderDesc:
+		return transactionsOrder{sortBy: sortBy, desc: true}, nil
+	default:
+		return transactionsOrder{}, fmt.Errorf("%w: %s, must be one of: asc, desc", errInvalidSortOrder, order)
+	}
+}
+
+// compare сравнивает две транзакции. ID используется как дополнительный ключ,
+// чтобы порядок транзакций с одинаковым значением поля был стабильным.
+func (o transactionsOrder) compare(a, b models.Transaction) int {
+	var result int
+
+	switch o.sortBy {
+	case models.SortByAmount:
+		result = cmp.Compare(a.Amount, b.Amount)
+	case models.SortByTitle:
+		result = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	default:
+		result = a.Date.Compare(b.Date)
+	}
+
+	if result == 0 {
+		result = strings.Compare(a.ID, b.ID)
+	}
+
+	if o.desc {
+		return -result
+	}
+
+	return result
+}
+
+func (o transactionsOrder) encodeCursor(last models.Transaction) string {
+	order := models.SortOrderAsc
+	if o.desc {
+		order = models.SortOrderDesc
+	}
+
+	buf, _ := json.Marshal(transactionsCursor{
+		SortBy: o.sortBy,
+		Order:  order,
+		Date:   last.Date,
+		Amount: last.Amount,
+		Title:  last.Title,
+		ID:     last.ID,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// decodeCursor возвращает порядок сортировки, сохраненный в курсоре,
+// и транзакцию-ключ, после которой начинается следующая страница
+func decodeCursor(cursor string) (transactionsOrder, models.Transaction, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return transactionsOrder{}, models.Transaction{}, fmt.Errorf("%w: %w", errInvalidCursor, err)
+	}
+
+	var decoded transactionsCursor
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		return transactionsOrder{}, models.Transaction{}, fmt.Errorf("%w: %w", errInvalidCursor, err)
+	}
+
+	order, err := newTransactionsOrder(decoded.SortBy, decoded.Order)
+	if err != nil {
+		return transactionsOrder{}, models.Transaction{}, fmt.Errorf("%w: %w", errInvalidCursor, err)
+	}
+
+	return order, models.Transaction{
+		ID:     decoded.ID,
+		Amount: decoded.Amount,
+		Title:  decoded.Title,
+		Date:   decoded.Date,
+	}, nil
+}
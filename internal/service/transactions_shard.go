@@ -0,0 +1,174 @@
+package service
+
+import (
+	"iter"
+	"strings"
+	"sync"
+
+	"spendings-backend/internal/models"
+)
+
+// userShard хранит транзакции одного пользователя вместе с индексами.
+// Операции разных пользователей не блокируют друг друга.
+type userShard struct {
+	// seedOnce гарантирует, что начальные транзакции добавятся ровно один раз
+	seedOnce sync.Once
+
+	mux            sync.RWMutex
+	transactions   map[string]models.Transaction // transactionID -> transaction
+	searchIndex    *searchIndex
+	dateIndex      *dateIndex
+	duplicateIndex *duplicateIndex
+	tagIndex       *tagIndex
+	suggestIndex   *suggestIndex
+	receiptIndex   map[string]string // ключ чека -> transactionID
+	// trash удаленные транзакции: они не попадают в индексы, список и статистику
+	trash map[string]models.Transaction // transactionID -> transaction
+}
+
+func newUserShard() *userShard {
+	return &userShard{
+		transactions:   make(map[string]models.Transaction),
+		searchIndex:    newSearchIndex(),
+		dateIndex:      newDateIndex(),
+		duplicateIndex: newDuplicateIndex(),
+		tagIndex:       newTagIndex(),
+		suggestIndex:   newSuggestIndex(),
+		receiptIndex:   make(map[string]string),
+		trash:          make(map[string]models.Transaction),
+	}
+}
+
+// put сохраняет транзакцию и обновляет индексы. Вызывается под блокировкой шарда на запись.
+func (us *userShard) put(transaction models.Transaction) {
+	if previous, exists := us.transactions[transaction.ID]; exists {
+		us.dateIndex.remove(previous)
+		us.duplicateIndex.remove(previous)
+		us.tagIndex.remove(previous)
+		us.suggestIndex.remove(previous)
+		us.removeReceipt(previous)
+	}
+
+	us.transactions[transaction.ID] = transaction
+	us.searchIndex.add(transaction)
+	us.dateIndex.add(transaction)
+	us.duplicateIndex.add(transaction)
+	us.tagIndex.add(transaction)
+	us.suggestIndex.add(transaction)
+	if transaction.Receipt != nil {
+		us.receiptIndex[receiptKey(*transaction.Receipt)] = transaction.ID
+	}
+}
+
+// remove удаляет транзакцию и ее записи в индексах. Вызывается под блокировкой шарда на запись.
+func (us *userShard) remove(id string) (models.Transaction, bool) {
+	transaction, exists := us.transactions[id]
+	if !exists {
+		return models.Transaction{}, false
+	}
+
+	delete(us.transactions, id)
+	us.searchIndex.remove(id)
+	us.dateIndex.remove(transaction)
+	us.duplicateIndex.remove(transaction)
+	us.tagIndex.remove(transaction)
+	us.suggestIndex.remove(transaction)
+	us.removeReceipt(transaction)
+
+	return transaction, true
+}
+
+// removeReceipt удаляет чек транзакции из индекса, если чек указывает на нее
+func (us *userShard) removeReceipt(transaction models.Transaction) {
+	if transaction.Receipt == nil {
+		return
+	}
+
+	key := receiptKey(*transaction.Receipt)
+	if us.receiptIndex[key] == transaction.ID {
+		delete(us.receiptIndex, key)
+	}
+}
+
+// allTransactions перебирает транзакции шарда вместе с корзиной. Вызывается под блокировкой шарда.
+func (us *userShard) allTransactions() iter.Seq2[string, models.Transaction] {
+	return func(yield func(string, models.Transaction) bool) {
+		for transactionID, transaction := range us.transactions {
+			if !yield(transactionID, transaction) {
+				return
+			}
+		}
+		for transactionID, transaction := range us.trash {
+			if !yield(transactionID, transaction) {
+				return
+			}
+		}
+	}
+}
+
+// selectTransactions возвращает транзакции, подходящие под фильтр, в произвольном порядке.
+// При поиске по тексту перебираются только найденные по индексу транзакции, иначе - только
+// транзакции из нужного диапазона дат. Вызывается под блокировкой шарда.
+func (us *userShard) selectTransactions(filter models.TransactionsFilter) []models.Transaction {
+	var selected []models.Transaction
+	if strings.TrimSpace(filter.Query) != "" {
+		for id := range us.searchIndex.search(filter.Query) {
+			if transaction, ok := us.transactions[id]; ok && matchesFilter(transaction, filter) {
+				selected = append(selected, transaction)
+			}
+		}
+	} else {
+		for _, entry := range us.dateIndex.rangeOf(filter.FromDate, filter.ToDate) {
+			if transaction := us.transactions[entry.id]; matchesFilter(transaction, filter) {
+				selected = append(selected, transaction)
+			}
+		}
+	}
+
+	return selected
+}
+
+// seed заполняет шард начальными транзакциями при первом обращении к нему
+func (us *userShard) seed(initial func() map[string]models.Transaction) {
+	us.seedOnce.Do(func() {
+		us.mux.Lock()
+		defer us.mux.Unlock()
+
+		for _, transaction := range initial() {
+			us.put(transaction)
+		}
+	})
+}
+
+// userShard возвращает шард пользователя, создавая и заполняя его начальными данными при первом обращении
+func (ts *TransactionsService) userShard(userID string) *userShard {
+	ts.mux.RLock()
+	shard, exists := ts.shards[userID]
+	ts.mux.RUnlock()
+
+	if !exists {
+		ts.mux.Lock()
+		if shard, exists = ts.shards[userID]; !exists {
+			shard = newUserShard()
+			ts.shards[userID] = shard
+		}
+		ts.mux.Unlock()
+	}
+
+	shard.seed(getInitialTransactions)
+
+	return shard
+}
+
+// allShards возвращает снимок списка шардов всех пользователей
+func (ts *TransactionsService) allShards() map[string]*userShard {
+	ts.mux.RLock()
+	defer ts.mux.RUnlock()
+
+	shards := make(map[string]*userShard, len(ts.shards))
+	for userID, shard := range ts.shards {
+		shards[userID] = shard
+	}
+
+	return shards
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"spendings-backend/internal/models"
+)
+
+// Стресс-тесты рассчитаны на запуск с детектором гонок: go test -race ./...
+const (
+	stressUsers      = 4
+	stressWriters    = 4
+	stressReaders    = 2
+	stressOperations = 100
+)
+
+func newStressTransactionsService(t *testing.T) *TransactionsService {
+	categories := NewCategoriesService(nil, models.GetDefaultBaseCategories())
+	attachments := NewAttachmentsService(t.TempDir(), 1<<20, zap.NewNop().Sugar())
+
+	return NewTransactionsService(nil, categories, NewRulesService(nil), attachments, time.Hour)
+}
+
+// stressWriter меняет только свои транзакции, поэтому знает их итоговое состояние
+type stressWriter struct {
+	ts  *TransactionsService
+	ctx context.Context
+	// live транзакции в списке: ID -> название
+	live map[string]string
+	// trashed транзакции в корзине
+	trashed map[string]struct{}
+}
+
+func (sw *stressWriter) create(t *testing.T, title string, day int) (string, bool) {
+	response, err := sw.ts.CreateTransaction(sw.ctx, models.CreateTransactionRequest{
+		Amount:   float64(100 + day),
+		Title:    title,
+		Category: "Еда",
+		Date:     time.Date(2026, time.January, 1+day%300, 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+		Tags:     []string{"stress"},
+	})
+	if !assert.NoError(t, err) {
+		return "", false
+	}
+	sw.live[response.ID] = title
+
+	return response.ID, true
+}
+
+// run выполняется в отдельной горутине, поэтому при ошибке проверки останавливается сам, без FailNow
+func (sw *stressWriter) run(t *testing.T, writer int) {
+	var previous string
+	for i := range stressOperations {
+		id, ok := sw.create(t, fmt.Sprintf("Покупка %d-%d", writer, i), i)
+		if !ok {
+			return
+		}
+
+		switch {
+		case i%3 == 0:
+			title := fmt.Sprintf("Обновлено %d-%d", writer, i)
+			result, err := sw.ts.BatchTransactions(sw.ctx, models.BatchRequest{Operations: []models.BatchOperation{{
+				Op:     models.BatchUpdate,
+				IDs:    []string{id},
+				Update: &models.TransactionUpdate{Title: &title, Tags: &[]string{"stress", "updated"}},
+			}}})
+			if !assert.NoError(t, err) || !assert.True(t, result.Applied) {
+				return
+			}
+			sw.live[id] = title
+		case i%5 == 0:
+			if !assert.NoError(t, sw.ts.DeleteTransaction(sw.ctx, id)) {
+				return
+			}
+			delete(sw.live, id)
+			sw.trashed[id] = struct{}{}
+
+			if i%10 == 0 {
+				if _, err := sw.ts.RestoreTransaction(sw.ctx, id); !assert.NoError(t, err) {
+					return
+				}
+				delete(sw.trashed, id)
+				sw.live[id] = fmt.Sprintf("Покупка %d-%d", writer, i)
+			}
+		case i%7 == 0 && previous != "":
+			// Пакет создает транзакцию и удаляет предыдущую под одной блокировкой шарда
+			title := fmt.Sprintf("Пакет %d-%d", writer, i)
+			result, err := sw.ts.BatchTransactions(sw.ctx, models.BatchRequest{Operations: []models.BatchOperation{
+				{Op: models.BatchCreate, Transaction: &models.CreateTransactionRequest{
+					Amount: 50, Title: title, Category: "Транспорт", Date: "2026-02-01",
+				}},
+				{Op: models.BatchDelete, IDs: []string{previous}},
+			}})
+			if !assert.NoError(t, err) || !assert.True(t, result.Applied) {
+				return
+			}
+			sw.live[result.Results[0].ID] = title
+			delete(sw.live, previous)
+			sw.trashed[previous] = struct{}{}
+		}
+
+		if _, exists := sw.live[id]; exists {
+			previous = id
+		}
+	}
+}
+
+// stressRead выполняет запросы чтения, которые обходят индексы шарда
+func stressRead(t *testing.T, ts *TransactionsService, ctx context.Context) {
+	_, err := ts.GetTransactions(ctx, models.TransactionsFilter{Query: "покупка"}, models.TransactionsPagination{Page: 1, PageSize: 20})
+	assert.NoError(t, err)
+	_, err = ts.GetTransactions(ctx, models.TransactionsFilter{Categories: []string{"Еда"}}, models.TransactionsPagination{Page: 2, PageSize: 20})
+	assert.NoError(t, err)
+	_, err = ts.GetAllTransactions(ctx, time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	assert.NoError(t, err)
+	_, err = ts.GetTags(ctx, "st", 10)
+	assert.NoError(t, err)
+	_, err = ts.SuggestCategories(ctx, "Покупка", nil, 3)
+	assert.NoError(t, err)
+	_, err = ts.GetDuplicates(ctx)
+	assert.NoError(t, err)
+	_, err = ts.GetTrash(ctx)
+	assert.NoError(t, err)
+}
+
+func TestTransactionsConcurrentUsers(t *testing.T) {
+	ts := newStressTransactionsService(t)
+
+	writers := make([][]*stressWriter, stressUsers)
+	for user := range writers {
+		ctx := userContext(fmt.Sprintf("user-%d", user))
+		for range stressWriters {
+			writers[user] = append(writers[user], &stressWriter{
+				ts:      ts,
+				ctx:     ctx,
+				live:    make(map[string]string),
+				trashed: make(map[string]struct{}),
+			})
+		}
+	}
+
+	// Читатели, фоновые задачи и писатели всех пользователей работают одновременно
+	var wg sync.WaitGroup
+	for user := range writers {
+		ctx := userContext(fmt.Sprintf("user-%d", user))
+		for range stressReaders {
+			wg.Go(func() {
+				for range stressOperations {
+					stressRead(t, ts, ctx)
+				}
+			})
+		}
+		for writer, sw := range writers[user] {
+			wg.Go(func() {
+				sw.run(t, writer)
+			})
+		}
+	}
+	// Фоновые задачи обходят шарды всех пользователей
+	wg.Go(func() {
+		for range stressOperations {
+			ts.PurgeExpiredTrash(time.Now().UTC())
+			ts.GetBackupData()
+		}
+	})
+	wg.Wait()
+
+	for user := range writers {
+		ctx := userContext(fmt.Sprintf("user-%d", user))
+
+		live := make(map[string]string)
+		trashed := make(map[string]struct{})
+		for _, sw := range writers[user] {
+			maps.Copy(live, sw.live)
+			maps.Copy(trashed, sw.trashed)
+		}
+		for id, transaction := range getInitialTransactions() {
+			live[id] = transaction.Title
+		}
+
+		transactions, err := ts.GetAllTransactions(ctx, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		got := make(map[string]string, len(transactions))
+		for _, transaction := range transactions {
+			got[transaction.ID] = transaction.Title
+		}
+		assert.Equal(t, live, got, "user %d", user)
+
+		trash, err := ts.GetTrash(ctx)
+		require.NoError(t, err)
+		gotTrash := make(map[string]struct{}, len(trash.Transactions))
+		for _, transaction := range trash.Transactions {
+			gotTrash[transaction.ID] = struct{}{}
+		}
+		assert.Equal(t, trashed, gotTrash, "user %d", user)
+
+		assertShardIndexes(t, ts.userShard(fmt.Sprintf("user-%d", user)))
+	}
+}
+
+func TestTransactionsConcurrentSeed(t *testing.T) {
+	ts := newStressTransactionsService(t)
+	ctx := userContext("new-user")
+
+	// Первые обращения нового пользователя не должны добавить начальные транзакции дважды
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Go(func() {
+			_, err := ts.GetAllTransactions(ctx, time.Time{}, time.Time{})
+			assert.NoError(t, err)
+		})
+	}
+	wg.Wait()
+
+	transactions, err := ts.GetAllTransactions(ctx, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, transactions, len(getInitialTransactions()))
+	assertShardIndexes(t, ts.userShard("new-user"))
+}
+
+// assertShardIndexes сравнивает индексы шарда с индексами, построенными заново по его транзакциям
+func assertShardIndexes(t *testing.T, shard *userShard) {
+	t.Helper()
+
+	shard.mux.RLock()
+	defer shard.mux.RUnlock()
+
+	rebuilt := newUserShard()
+	for _, transaction := range shard.transactions {
+		rebuilt.put(transaction)
+	}
+
+	assert.Equal(t, rebuilt.dateIndex.entries, shard.dateIndex.entries, "date index")
+	assert.Equal(t, rebuilt.searchIndex, shard.searchIndex, "search index")
+	assert.Equal(t, rebuilt.tagIndex, shard.tagIndex, "tag index")
+	assert.Equal(t, rebuilt.suggestIndex, shard.suggestIndex, "suggest index")
+	assert.Equal(t, rebuilt.receiptIndex, shard.receiptIndex, "receipt index")
+	assert.Equal(t, sortedGroups(rebuilt.duplicateIndex), sortedGroups(shard.duplicateIndex), "duplicate index")
+
+	for id := range shard.trash {
+		assert.NotContains(t, shard.transactions, id, "transaction %s is both in list and trash", id)
+	}
+}
+
+// sortedGroups возвращает группы дубликатов без учета порядка добавления
+func sortedGroups(index *duplicateIndex) map[string][]string {
+	groups := make(map[string][]string, len(index.groups))
+	for fingerprint, ids := range index.groups {
+		groups[fingerprint] = slices.Sorted(slices.Values(ids))
+	}
+
+	return groups
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"spendings-backend/internal/models"
+)
+
+// moveToTrash убирает транзакцию из списка и индексов и кладет ее в корзину.
+// Вызывается под блокировкой шарда на запись.
+func (us *userShard) moveToTrash(id string, now time.Time) (models.Transaction, bool) {
+	transaction, removed := us.remove(id)
+	if !removed {
+		return models.Transaction{}, false
+	}
+
+	transaction.DeletedAt = &now
+	us.trash[id] = transaction
+
+	return transaction, true
+}
+
+// GetTrash возвращает удаленные транзакции пользователя, сначала удаленные последними
+func (ts *TransactionsService) GetTrash(ctx context.Context) (*models.TrashResponse, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	shard := ts.userShard(userID)
+
+	shard.mux.RLock()
+	transactions := make([]models.Transaction, 0, len(shard.trash))
+	for _, transaction := range shard.trash {
+		transactions = append(transactions, transaction)
+	}
+	shard.mux.RUnlock()
+
+	slices.SortFunc(transactions, func(a, b models.Transaction) int {
+		if c := b.DeletedAt.Compare(*a.DeletedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return &models.TrashResponse{
+		RetentionDays: int(ts.trashRetention / (24 * time.Hour)),
+		Transactions:  transactions,
+	}, nil
+}
+
+// RestoreTransaction возвращает транзакцию из корзины в список. Если чек транзакции
+// за это время отсканировали заново, восстановление создало бы дубликат чека.
+func (ts *TransactionsService) RestoreTransaction(ctx context.Context, id string) (*models.Transaction, error) {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	shard := ts.userShard(userID)
+
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	transaction, exists := shard.trash[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: transaction %s not found in trash", models.ErrNotFound, id)
+	}
+	if transaction.Receipt != nil {
+		if existing, scanned := shard.receiptTransaction(*transaction.Receipt); scanned {
+			return nil, fmt.Errorf("%w: receipt of transaction %s belongs to transaction %s", models.ErrConflict, id, existing.ID)
+		}
+	}
+
+	delete(shard.trash, id)
+	transaction.DeletedAt = nil
+	shard.put(transaction)
+
+	return &transaction, nil
+}
+
+// PurgeTrashTransaction удаляет транзакцию из корзины навсегда вместе с вложениями
+func (ts *TransactionsService) PurgeTrashTransaction(ctx context.Context, id string) error {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	shard := ts.userShard(userID)
+
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	transaction, exists := shard.trash[id]
+	if !exists {
+		return fmt.Errorf("%w: transaction %s not found in trash", models.ErrNotFound, id)
+	}
+
+	delete(shard.trash, id)
+	ts.attachments.Remove(userID, transaction.Attachments)
+
+	return nil
+}
+
+// EmptyTrash удаляет навсегда все транзакции из корзины пользователя
+func (ts *TransactionsService) EmptyTrash(ctx context.Context) error {
+	userID := models.ClaimsFromContext(ctx).ID
+
+	shard := ts.userShard(userID)
+
+	shard.mux.Lock()
+	defer shard.mux.Unlock()
+
+	ts.purgeTrash(userID, shard, func(models.Transaction) bool { return true })
+
+	return nil
+}
+
+// PurgeExpiredTrash удаляет навсегда транзакции, которые пролежали в корзине дольше срока хранения
+func (ts *TransactionsService) PurgeExpiredTrash(now time.Time) int {
+	expiredBefore := now.Add(-ts.trashRetention)
+
+	purged := 0
+	// Обрабатываем всех пользователей, блокируя каждого по отдельности
+	for userID, shard := range ts.allShards() {
+		shard.mux.Lock()
+		purged += ts.purgeTrash(userID, shard, func(transaction models.Transaction) bool {
+			return transaction.DeletedAt.Before(expiredBefore)
+		})
+		shard.mux.Unlock()
+	}
+
+	return purged
+}
+
+// purgeTrash удаляет из корзины подходящие транзакции и их вложения. Вызывается под блокировкой шарда на запись.
+func (ts *TransactionsService) purgeTrash(userID string, shard *userShard, purge func(models.Transaction) bool) int {
+	var attachments []models.Attachment
+	purged := 0
+	for transactionID, transaction := range shard.trash {
+		if !purge(transaction) {
+			continue
+		}
+		delete(shard.trash, transactionID)
+		attachments = append(attachments, transaction.Attachments...)
+		purged++
+	}
+
+	if len(attachments) > 0 {
+		ts.attachments.Remove(userID, attachments)
+	}
+
+	return purged
+}
+
+// TrashPurgeService периодически очищает корзины пользователей от просроченных транзакций
+type TrashPurgeService struct {
+	transactionsService *TransactionsService
+	logger              *zap.SugaredLogger
+}
+
+// NewTrashPurgeService создает сервис очистки корзины
+func NewTrashPurgeService(transactionsService *TransactionsService, logger *zap.SugaredLogger) *TrashPurgeService {
+	return &TrashPurgeService{
+		transactionsService: transactionsService,
+		logger:              logger,
+	}
+}
+
+// Start очищает корзины при запуске и затем каждый час
+func (tps *TrashPurgeService) Start(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	tps.purge()
+
+	for {
+		select {
+		case <-ticker.C:
+			tps.purge()
+		case <-ctx.Done():
+			tps.logger.Info("Trash purge service stopped by context")
+			return
+		}
+	}
+}
+
+func (tps *TrashPurgeService) purge() {
+	if purged := tps.transactionsService.PurgeExpiredTrash(time.Now().UTC()); purged > 0 {
+		tps.logger.Infof("Purged %d expired transactions from trash", purged)
+	}
+}
@@ -0,0 +1,292 @@
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf16"
+)
+
+// Размер страницы A4 в пунктах
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Color цвет в RGB, компоненты от 0 до 1
+type Color struct {
+	R, G, B float64
+}
+
+// Point точка на странице. Координаты отсчитываются от левого верхнего угла.
+type Point struct {
+	X, Y float64
+}
+
+// Document PDF-документ из страниц A4 с одним встроенным шрифтом TrueType.
+// Текст кодируется номерами глифов (Identity-H), а таблица ToUnicode позволяет
+// искать и копировать текст в просмотрщике.
+type Document struct {
+	font  *Font
+	title string
+	pages []*Page
+	// used глифы, которые встречаются в тексте документа, и их символы
+	used map[uint16]rune
+}
+
+// Page страница документа
+type Page struct {
+	document *Document
+	content  bytes.Buffer
+}
+
+// NewDocument создает пустой документ со шрифтом font
+func NewDocument(font *Font, title string) *Document {
+	return &Document{
+		font:  font,
+		title: title,
+		used:  make(map[uint16]rune),
+	}
+}
+
+// AddPage добавляет в документ новую страницу
+func (d *Document) AddPage() *Page {
+	page := &Page{document: d}
+	d.pages = append(d.pages, page)
+
+	return page
+}
+
+// PageCount возвращает количество страниц документа
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// TextWidth возвращает ширину текста в пунктах при кегле size
+func (d *Document) TextWidth(text string, size float64) float64 {
+	var width float64
+	for _, r := range text {
+		width += d.font.advance(d.font.glyph(r))
+	}
+
+	return width * size / 1000
+}
+
+// Text выводит строку текста. y - положение базовой линии от верхнего края страницы.
+func (p *Page) Text(x, y, size float64, color Color, text string) {
+	var glyphs strings.Builder
+	for _, r := range text {
+		glyph := p.document.font.glyph(r)
+		if _, exists := p.document.used[glyph]; !exists && glyph != 0 {
+			p.document.used[glyph] = r
+		}
+		fmt.Fprintf(&glyphs, "%04X", glyph)
+	}
+
+	fmt.Fprintf(&p.content, "BT /F1 %s Tf %s rg %s %s Td <%s> Tj ET\n",
+		number(size), color.operands(), number(x), number(PageHeight-y), glyphs.String())
+}
+
+// Line рисует отрезок
+func (p *Page) Line(from, to Point, width float64, color Color) {
+	p.Polyline([]Point{from, to}, width, color)
+}
+
+// Polyline рисует ломаную линию
+func (p *Page) Polyline(points []Point, width float64, color Color) {
+	if len(points) < 2 {
+		return
+	}
+
+	fmt.Fprintf(&p.content, "%s w %s RG ", number(width), color.operands())
+	for i, point := range points {
+		operator := "l"
+		if i == 0 {
+			operator = "m"
+		}
+		fmt.Fprintf(&p.content, "%s %s %s ", number(point.X), number(PageHeight-point.Y), operator)
+	}
+	p.content.WriteString("S\n")
+}
+
+// Rect рисует закрашенный прямоугольник с левым верхним углом в (x, y)
+func (p *Page) Rect(x, y, width, height float64, color Color) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n",
+		color.operands(), number(x), number(PageHeight-y-height), number(width), number(height))
+}
+
+// WriteTo записывает документ в формате PDF 1.7
+func (d *Document) WriteTo(writer io.Writer) (int64, error) {
+	out := &countingWriter{writer: bufio.NewWriter(writer)}
+
+	// Номера объектов: 1 - каталог, 2 - дерево страниц, 3-7 - шрифт, 8 - сведения о документе,
+	// далее по два объекта на страницу: сама страница и ее содержимое
+	const firstPageObject = 9
+
+	offsets := make([]int64, 0, firstPageObject+len(d.pages)*2)
+	object := func(body string) {
+		offsets = append(offsets, out.written)
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	stream := func(dictionary string, data []byte) {
+		offsets = append(offsets, out.written)
+		compressed := deflate(data)
+		fmt.Fprintf(out, "%d 0 obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", len(offsets), dictionary, len(compressed))
+		out.Write(compressed)
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	out.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObject+i*2)
+	}
+
+	fontName := "AAAAAA+" + d.font.name
+	fontFile := d.font.subset(d.used)
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [4 0 R] /ToUnicode 7 0 R >>", fontName))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor 5 0 R /CIDToGIDMap /Identity /DW %d /W [%s] >>",
+		fontName, int(d.font.advance(0)), d.widths()))
+	object(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] "+
+		"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 6 0 R >>",
+		fontName,
+		d.font.scale(d.font.bbox[0]), d.font.scale(d.font.bbox[1]), d.font.scale(d.font.bbox[2]), d.font.scale(d.font.bbox[3]),
+		d.font.scale(d.font.ascent), d.font.scale(d.font.descent), d.font.scale(d.font.ascent)))
+	stream(fmt.Sprintf("/Length1 %d", len(fontFile)), fontFile)
+	stream("", d.toUnicode())
+	object(fmt.Sprintf("<< /Title %s /Producer (spendings-backend) >>", textString(d.title)))
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			number(PageWidth), number(PageHeight), firstPageObject+i*2+1))
+		stream("", page.content.Bytes())
+	}
+
+	xref := out.written
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R /Info 8 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	if err := out.writer.Flush(); err != nil && out.err == nil {
+		out.err = err
+	}
+
+	return out.written, out.err
+}
+
+// widths возвращает массив ширин использованных глифов для словаря /W
+func (d *Document) widths() string {
+	glyphs := d.usedGlyphs()
+
+	var widths strings.Builder
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", glyph, int(d.font.advance(glyph)))
+	}
+
+	return strings.TrimSpace(widths.String())
+}
+
+// toUnicode строит таблицу CMap, по которой просмотрщик восстанавливает текст из глифов
+func (d *Document) toUnicode() []byte {
+	var cmap bytes.Buffer
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	glyphs := d.usedGlyphs()
+	// В одном блоке bfchar допускается не больше 100 записей
+	for chunk := range slices.Chunk(glyphs, 100) {
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(chunk))
+		for _, glyph := range chunk {
+			fmt.Fprintf(&cmap, "<%04X> <", glyph)
+			for _, unit := range utf16.Encode([]rune{d.used[glyph]}) {
+				fmt.Fprintf(&cmap, "%04X", unit)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+
+	return cmap.Bytes()
+}
+
+func (d *Document) usedGlyphs() []uint16 {
+	glyphs := make([]uint16, 0, len(d.used))
+	for glyph := range d.used {
+		glyphs = append(glyphs, glyph)
+	}
+	slices.Sort(glyphs)
+
+	return glyphs
+}
+
+func (c Color) operands() string {
+	return number(c.R) + " " + number(c.G) + " " + number(c.B)
+}
+
+// number форматирует число для потока PDF без экспоненты и лишних нулей
+func number(value float64) string {
+	formatted := strings.TrimRight(fmt.Sprintf("%.3f", value), "0")
+
+	return strings.TrimSuffix(formatted, ".")
+}
+
+// textString кодирует строку Unicode для словарей PDF (UTF-16BE с BOM)
+func textString(text string) string {
+	var encoded strings.Builder
+	encoded.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&encoded, "%04X", unit)
+	}
+	encoded.WriteString(">")
+
+	return encoded.String()
+}
+
+func deflate(data []byte) []byte {
+	var compressed bytes.Buffer
+
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(data)
+	writer.Close()
+
+	return compressed.Bytes()
+}
+
+// countingWriter считает записанные байты для таблицы xref и запоминает первую ошибку записи
+type countingWriter struct {
+	writer  *bufio.Writer
+	written int64
+	err     error
+}
+
+func (cw *countingWriter) Write(data []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+
+	n, err := cw.writer.Write(data)
+	cw.written += int64(n)
+	cw.err = err
+
+	return n, err
+}
+
+func (cw *countingWriter) WriteString(data string) (int, error) {
+	return cw.Write([]byte(data))
+}
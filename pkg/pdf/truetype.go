@@ -0,0 +1,497 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf16"
+)
+
+var (
+	errFontTooShort     = errors.New("font data is too short")
+	errMissingFontTable = errors.New("missing font table")
+	errNoUnicodeCmap    = errors.New("font has no unicode cmap")
+)
+
+// Таблицы, которые нужны просмотрщику PDF для отрисовки шрифта TrueType.
+// Остальные таблицы (кернинг, лигатуры, имена) при встраивании отбрасываются.
+var embeddedTables = []string{"cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep"}
+
+// Font шрифт TrueType, который можно встроить в документ
+type Font struct {
+	name       string
+	tables     map[string][]byte
+	unitsPerEm uint16
+	bbox       [4]int16
+	ascent     int16
+	descent    int16
+	advances   []uint16
+	glyphs     map[rune]uint16
+	locations  []uint32
+}
+
+// ParseTrueType разбирает шрифт TrueType (.ttf)
+func ParseTrueType(data []byte) (*Font, error) {
+	if len(data) < 12 {
+		return nil, errFontTooShort
+	}
+
+	font := &Font{tables: make(map[string][]byte)}
+
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+numTables*16 {
+		return nil, errFontTooShort
+	}
+
+	for i := range numTables {
+		record := data[12+i*16:]
+		tag := string(record[:4])
+		offset := binary.BigEndian.Uint32(record[8:])
+		length := binary.BigEndian.Uint32(record[12:])
+
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("table %q is out of bounds: %w", tag, errFontTooShort)
+		}
+		font.tables[tag] = data[offset : offset+length]
+	}
+
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if _, exists := font.tables[tag]; !exists {
+			return nil, fmt.Errorf("%w %q", errMissingFontTable, tag)
+		}
+	}
+
+	if err := font.parseMetrics(); err != nil {
+		return nil, err
+	}
+
+	if err := font.parseCmap(); err != nil {
+		return nil, err
+	}
+
+	font.name = font.parseName()
+
+	return font, nil
+}
+
+func (f *Font) parseMetrics() error {
+	head, hhea, maxp := f.tables["head"], f.tables["hhea"], f.tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return errFontTooShort
+	}
+
+	f.unitsPerEm = binary.BigEndian.Uint16(head[18:])
+	for i := range f.bbox {
+		f.bbox[i] = int16(binary.BigEndian.Uint16(head[36+i*2:]))
+	}
+	f.ascent = int16(binary.BigEndian.Uint16(hhea[4:]))
+	f.descent = int16(binary.BigEndian.Uint16(hhea[6:]))
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	numberOfHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+
+	hmtx := f.tables["hmtx"]
+	if numberOfHMetrics == 0 || len(hmtx) < numberOfHMetrics*4 {
+		return fmt.Errorf("hmtx: %w", errFontTooShort)
+	}
+
+	// Глифы после numberOfHMetrics используют ширину последней записи
+	f.advances = make([]uint16, numGlyphs)
+	for i := range f.advances {
+		f.advances[i] = binary.BigEndian.Uint16(hmtx[min(i, numberOfHMetrics-1)*4:])
+	}
+
+	loca := f.tables["loca"]
+	longOffsets := binary.BigEndian.Uint16(head[50:]) == 1
+
+	f.locations = make([]uint32, numGlyphs+1)
+	for i := range f.locations {
+		if longOffsets {
+			if len(loca) < (i+1)*4 {
+				return fmt.Errorf("loca: %w", errFontTooShort)
+			}
+			f.locations[i] = binary.BigEndian.Uint32(loca[i*4:])
+		} else {
+			if len(loca) < (i+1)*2 {
+				return fmt.Errorf("loca: %w", errFontTooShort)
+			}
+			f.locations[i] = uint32(binary.BigEndian.Uint16(loca[i*2:])) * 2
+		}
+	}
+
+	return nil
+}
+
+// parseCmap читает таблицу соответствия символов Unicode глифам (форматы 4 и 12)
+func (f *Font) parseCmap() error {
+	cmap := f.tables["cmap"]
+	if len(cmap) < 4 {
+		return errFontTooShort
+	}
+
+	var format4, format12 []byte
+
+	numTables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := range numTables {
+		if len(cmap) < 4+(i+1)*8 {
+			return fmt.Errorf("cmap: %w", errFontTooShort)
+		}
+		record := cmap[4+i*8:]
+		platformID := binary.BigEndian.Uint16(record)
+		encodingID := binary.BigEndian.Uint16(record[2:])
+		offset := binary.BigEndian.Uint32(record[4:])
+		if int(offset)+4 > len(cmap) {
+			continue
+		}
+
+		subtable := cmap[offset:]
+		isUnicode := platformID == 0 || (platformID == 3 && (encodingID == 1 || encodingID == 10))
+		if !isUnicode {
+			continue
+		}
+
+		switch binary.BigEndian.Uint16(subtable) {
+		case 4:
+			format4 = subtable
+		case 12:
+			format12 = subtable
+		}
+	}
+
+	f.glyphs = make(map[rune]uint16)
+
+	switch {
+	case format12 != nil:
+		return f.parseCmapFormat12(format12)
+	case format4 != nil:
+		return f.parseCmapFormat4(format4)
+	default:
+		return errNoUnicodeCmap
+	}
+}
+
+func (f *Font) parseCmapFormat4(subtable []byte) error {
+	if len(subtable) < 14 {
+		return fmt.Errorf("cmap format 4: %w", errFontTooShort)
+	}
+
+	segCount := int(binary.BigEndian.Uint16(subtable[6:])) / 2
+	endCodes := 14
+	startCodes := endCodes + segCount*2 + 2
+	idDeltas := startCodes + segCount*2
+	idRangeOffsets := idDeltas + segCount*2
+	if len(subtable) < idRangeOffsets+segCount*2 {
+		return fmt.Errorf("cmap format 4: %w", errFontTooShort)
+	}
+
+	for segment := range segCount {
+		end := int(binary.BigEndian.Uint16(subtable[endCodes+segment*2:]))
+		start := int(binary.BigEndian.Uint16(subtable[startCodes+segment*2:]))
+		delta := binary.BigEndian.Uint16(subtable[idDeltas+segment*2:])
+		rangeOffsetPosition := idRangeOffsets + segment*2
+		rangeOffset := int(binary.BigEndian.Uint16(subtable[rangeOffsetPosition:]))
+
+		for code := start; code <= end && code != 0xFFFF; code++ {
+			var glyph uint16
+			if rangeOffset == 0 {
+				glyph = uint16(code) + delta
+			} else {
+				position := rangeOffsetPosition + rangeOffset + (code-start)*2
+				if position+2 > len(subtable) {
+					continue
+				}
+				if glyph = binary.BigEndian.Uint16(subtable[position:]); glyph != 0 {
+					glyph += delta
+				}
+			}
+
+			if glyph != 0 {
+				f.glyphs[rune(code)] = glyph
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *Font) parseCmapFormat12(subtable []byte) error {
+	if len(subtable) < 16 {
+		return fmt.Errorf("cmap format 12: %w", errFontTooShort)
+	}
+
+	numGroups := int(binary.BigEndian.Uint32(subtable[12:]))
+	if len(subtable) < 16+numGroups*12 {
+		return fmt.Errorf("cmap format 12: %w", errFontTooShort)
+	}
+
+	for i := range numGroups {
+		group := subtable[16+i*12:]
+		start := binary.BigEndian.Uint32(group)
+		end := binary.BigEndian.Uint32(group[4:])
+		startGlyph := binary.BigEndian.Uint32(group[8:])
+
+		for code := start; code <= end && code <= 0x10FFFF; code++ {
+			f.glyphs[rune(code)] = uint16(startGlyph + code - start)
+		}
+	}
+
+	return nil
+}
+
+// parseName возвращает PostScript-имя шрифта из таблицы name
+func (f *Font) parseName() string {
+	const postScriptNameID = 6
+
+	name := f.tables["name"]
+	if len(name) < 6 {
+		return "Font"
+	}
+
+	count := int(binary.BigEndian.Uint16(name[2:]))
+	stringOffset := int(binary.BigEndian.Uint16(name[4:]))
+
+	for i := range count {
+		if len(name) < 6+(i+1)*12 {
+			break
+		}
+		record := name[6+i*12:]
+		platformID := binary.BigEndian.Uint16(record)
+		nameID := binary.BigEndian.Uint16(record[6:])
+		length := int(binary.BigEndian.Uint16(record[8:]))
+		offset := stringOffset + int(binary.BigEndian.Uint16(record[10:]))
+
+		if nameID != postScriptNameID || offset+length > len(name) {
+			continue
+		}
+
+		value := name[offset : offset+length]
+		switch platformID {
+		case 1:
+			return string(value)
+		case 0, 3:
+			units := make([]uint16, len(value)/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(value[j*2:])
+			}
+			return string(utf16.Decode(units))
+		}
+	}
+
+	return "Font"
+}
+
+// HasRune сообщает, есть ли символ в шрифте
+func (f *Font) HasRune(r rune) bool {
+	_, exists := f.glyphs[r]
+
+	return exists
+}
+
+// glyph возвращает номер глифа для символа или 0 (.notdef), если символа нет в шрифте
+func (f *Font) glyph(r rune) uint16 {
+	return f.glyphs[r]
+}
+
+// advance возвращает ширину глифа в тысячных долях кегля
+func (f *Font) advance(glyph uint16) float64 {
+	if int(glyph) >= len(f.advances) {
+		return 0
+	}
+
+	return float64(f.advances[glyph]) * 1000 / float64(f.unitsPerEm)
+}
+
+// scale переводит значение из единиц шрифта в тысячные доли кегля
+func (f *Font) scale(value int16) int {
+	return int(value) * 1000 / int(f.unitsPerEm)
+}
+
+// subset собирает шрифт, в котором сохранены только данные использованных глифов.
+// Номера глифов не меняются, поэтому текст документа не нужно перекодировать.
+func (f *Font) subset(used map[uint16]rune) []byte {
+	keep := map[uint16]struct{}{0: {}}
+	queue := make([]uint16, 0, len(used))
+	for glyph := range used {
+		queue = append(queue, glyph)
+	}
+
+	// Составные глифы ссылаются на другие глифы, их тоже нужно сохранить
+	for len(queue) > 0 {
+		glyph := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		if _, exists := keep[glyph]; exists && glyph != 0 {
+			continue
+		}
+		keep[glyph] = struct{}{}
+		queue = append(queue, f.components(glyph)...)
+	}
+
+	glyf := f.tables["glyf"]
+	numGlyphs := len(f.locations) - 1
+
+	var newGlyf bytes.Buffer
+	newLoca := make([]byte, (numGlyphs+1)*4)
+
+	for glyph := range numGlyphs {
+		binary.BigEndian.PutUint32(newLoca[glyph*4:], uint32(newGlyf.Len()))
+
+		if _, exists := keep[uint16(glyph)]; !exists {
+			continue
+		}
+
+		start, end := f.locations[glyph], f.locations[glyph+1]
+		if start < end && int(end) <= len(glyf) {
+			newGlyf.Write(glyf[start:end])
+			for newGlyf.Len()%4 != 0 {
+				newGlyf.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[numGlyphs*4:], uint32(newGlyf.Len()))
+
+	head := slices.Clone(f.tables["head"])
+	// Смещения в новой таблице loca 32-битные
+	binary.BigEndian.PutUint16(head[50:], 1)
+	// Контрольная сумма шрифта пересчитывается после сборки
+	binary.BigEndian.PutUint32(head[8:], 0)
+
+	tables := map[string][]byte{
+		"glyf": newGlyf.Bytes(),
+		"loca": newLoca,
+		"head": head,
+	}
+	for _, tag := range embeddedTables {
+		if _, exists := tables[tag]; exists {
+			continue
+		}
+		if table, exists := f.tables[tag]; exists {
+			tables[tag] = table
+		}
+	}
+
+	return buildFont(tables)
+}
+
+// components возвращает глифы, из которых состоит составной глиф
+func (f *Font) components(glyph uint16) []uint16 {
+	const (
+		argsAreWords  = 0x0001
+		hasScale      = 0x0008
+		moreComponent = 0x0020
+		hasXYScale    = 0x0040
+		hasTwoByTwo   = 0x0080
+	)
+
+	if int(glyph)+1 >= len(f.locations) {
+		return nil
+	}
+
+	glyf := f.tables["glyf"]
+	start, end := int(f.locations[glyph]), int(f.locations[glyph+1])
+	if start+10 > end || end > len(glyf) {
+		return nil
+	}
+
+	data := glyf[start:end]
+	if int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+
+	var components []uint16
+	for position := 10; position+4 <= len(data); {
+		flags := binary.BigEndian.Uint16(data[position:])
+		components = append(components, binary.BigEndian.Uint16(data[position+2:]))
+		position += 4
+
+		if flags&argsAreWords != 0 {
+			position += 4
+		} else {
+			position += 2
+		}
+
+		switch {
+		case flags&hasScale != 0:
+			position += 2
+		case flags&hasXYScale != 0:
+			position += 4
+		case flags&hasTwoByTwo != 0:
+			position += 8
+		}
+
+		if flags&moreComponent == 0 {
+			break
+		}
+	}
+
+	return components
+}
+
+// buildFont собирает файл шрифта TrueType из таблиц
+func buildFont(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	numTables := len(tags)
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= numTables {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 16
+
+	var font bytes.Buffer
+	header := make([]byte, 12+numTables*16)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(numTables*16-searchRange))
+
+	offset := len(header)
+	headOffset := 0
+	for i, tag := range tags {
+		table := tables[tag]
+		record := header[12+i*16:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], checksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(offset))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+
+		if tag == "head" {
+			headOffset = offset
+		}
+		offset += (len(table) + 3) &^ 3
+	}
+
+	font.Write(header)
+	for _, tag := range tags {
+		font.Write(tables[tag])
+		for font.Len()%4 != 0 {
+			font.WriteByte(0)
+		}
+	}
+
+	result := font.Bytes()
+	if _, exists := tables["head"]; exists {
+		binary.BigEndian.PutUint32(result[headOffset+8:], 0xB1B0AFBA-checksum(result))
+	}
+
+	return result
+}
+
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+
+	return sum
+}